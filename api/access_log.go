@@ -0,0 +1,71 @@
+package api
+
+import (
+	"fmt"
+	"github.com/Tommych123/subscription-service/internal/logger"
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+	"math/rand/v2"
+	"net/http"
+	"runtime/debug"
+	"time"
+)
+
+// AccessLog пишет по одной записи zap на запрос в логгер запроса (с request_id). Запросы
+// к skipPaths не логируются, успешные — с вероятностью sampleRate. Подключается после
+// RequestID и до ErrorHandler, чтобы видеть итоговый статус ответа.
+func AccessLog(base *zap.Logger, sampleRate float64, skipPaths []string) gin.HandlerFunc {
+	skip := make(map[string]bool, len(skipPaths))
+	for _, p := range skipPaths {
+		skip[p] = true
+	}
+	return func(c *gin.Context) {
+		if skip[c.Request.URL.Path] || skip[c.FullPath()] {
+			c.Next()
+			return
+		}
+		start := time.Now()
+		c.Next()
+		status := c.Writer.Status()
+		if status < http.StatusBadRequest && rand.Float64() >= sampleRate {
+			return
+		}
+		log := logger.FromContext(c.Request.Context(), base)
+		// Шаблон маршрута (поле route) уже есть в логгере запроса; сам путь не пишется,
+		// чтобы идентификаторы из URL не попадали в журнал доступа.
+		log.Info("HTTP request",
+			zap.Int("status", status),
+			zap.Duration("latency", time.Since(start)),
+			zap.Int("bytes", max(c.Writer.Size(), 0)),
+			zap.String("client_ip", c.ClientIP()),
+			zap.String("user_agent", c.Request.UserAgent()),
+		)
+	}
+}
+
+// Recovery перехватывает панику, пишет её в лог со стеком и, если ответ ещё не начат,
+// отвечает 500 в формате problem+json. Подключается первым, чтобы паника в любом
+// middleware не обрывала соединение.
+func Recovery(base *zap.Logger) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		defer func() {
+			rec := recover()
+			if rec == nil {
+				return
+			}
+			if rec == http.ErrAbortHandler {
+				panic(rec)
+			}
+			logger.FromContext(c.Request.Context(), base).Error("Panic recovered",
+				zap.Any("panic", rec),
+				zap.ByteString("stack", debug.Stack()),
+			)
+			if c.Writer.Written() {
+				c.Abort()
+				return
+			}
+			writeProblem(c, newProblem(fmt.Errorf("panic: %v", rec)))
+		}()
+		c.Next()
+	}
+}
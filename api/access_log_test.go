@@ -0,0 +1,53 @@
+package api
+
+import (
+	"encoding/json"
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestRecovery(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	tests := []struct {
+		name       string
+		middleware gin.HandlerFunc
+		handler    gin.HandlerFunc
+	}{
+		{
+			name:       "handler panic",
+			middleware: func(c *gin.Context) { c.Next() },
+			handler:    func(c *gin.Context) { panic("boom") },
+		},
+		{
+			name:       "middleware panic",
+			middleware: func(c *gin.Context) { panic("boom") },
+			handler:    func(c *gin.Context) { c.Status(http.StatusNoContent) },
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := gin.New()
+			r.Use(Recovery(zap.NewNop()), RequestID(zap.NewNop()), ErrorHandler(zap.NewNop()), tt.middleware)
+			r.GET("/panic", tt.handler)
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/panic", nil))
+
+			if w.Code != http.StatusInternalServerError {
+				t.Fatalf("status = %d, want 500", w.Code)
+			}
+			if ct := w.Header().Get("Content-Type"); ct != problemContentType {
+				t.Errorf("Content-Type = %q, want %q", ct, problemContentType)
+			}
+			var problem Problem
+			if err := json.Unmarshal(w.Body.Bytes(), &problem); err != nil {
+				t.Fatalf("decode problem: %v", err)
+			}
+			if problem.Status != http.StatusInternalServerError || problem.Instance != "/panic" {
+				t.Errorf("problem = %+v, want status 500 for /panic", problem)
+			}
+		})
+	}
+}
@@ -0,0 +1,77 @@
+package api
+
+import (
+	"github.com/Tommych123/subscription-service/internal/logger"
+	"github.com/Tommych123/subscription-service/service/auth"
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"net/http"
+)
+
+// LogLevel — текущий уровень логирования.
+type LogLevel struct {
+	Level string `json:"level" example:"debug"`
+}
+
+// AdminHandler — служебные эндпоинты управления сервисом.
+type AdminHandler struct {
+	level  zap.AtomicLevel
+	logger *zap.Logger
+}
+
+func NewAdminHandler(level zap.AtomicLevel, logger *zap.Logger) *AdminHandler {
+	return &AdminHandler{level: level, logger: logger}
+}
+
+func (h *AdminHandler) RegisterRoutes(r gin.IRouter) {
+	admin := r.Group("/admin", RequirePermission(auth.PermAdmin))
+	{
+		admin.GET("/log-level", h.GetLogLevel)
+		admin.PUT("/log-level", h.SetLogLevel)
+	}
+}
+
+// GetLogLevel получить уровень логирования
+// @Summary Получить текущий уровень логирования
+// @Tags admin
+// @Produce json
+// @Success 200 {object} api.LogLevel
+// @Failure 401 {object} api.Problem "Нет или недействительный токен"
+// @Failure 403 {object} api.Problem "Нет права admin"
+// @Failure 429 {object} api.Problem "Превышен лимит запросов"
+// @Security BearerAuth
+// @Router /admin/log-level [get]
+func (h *AdminHandler) GetLogLevel(c *gin.Context) {
+	c.JSON(http.StatusOK, LogLevel{Level: h.level.String()})
+}
+
+// SetLogLevel изменить уровень логирования
+// @Summary Изменить уровень логирования без перезапуска
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Param level body api.LogLevel true "Новый уровень: debug, info, warn, error"
+// @Success 200 {object} api.LogLevel
+// @Failure 400 {object} api.Problem "Неизвестный уровень"
+// @Failure 401 {object} api.Problem "Нет или недействительный токен"
+// @Failure 403 {object} api.Problem "Нет права admin"
+// @Failure 429 {object} api.Problem "Превышен лимит запросов"
+// @Security BearerAuth
+// @Router /admin/log-level [put]
+func (h *AdminHandler) SetLogLevel(c *gin.Context) {
+	var req LogLevel
+	if err := c.ShouldBindJSON(&req); err != nil {
+		_ = c.Error(invalidInput("%v", err))
+		return
+	}
+	level, err := zapcore.ParseLevel(req.Level)
+	if err != nil {
+		_ = c.Error(invalidInput("%v", err))
+		return
+	}
+	previous := h.level.Level()
+	h.level.SetLevel(level)
+	logger.FromContext(c.Request.Context(), h.logger).Warn("Log level changed", zap.Stringer("from", previous), zap.Stringer("to", level))
+	c.JSON(http.StatusOK, LogLevel{Level: level.String()})
+}
@@ -0,0 +1,99 @@
+package api
+
+import (
+	"github.com/Tommych123/subscription-service/models"
+	"github.com/Tommych123/subscription-service/service"
+	"github.com/Tommych123/subscription-service/service/auth"
+	"github.com/gin-gonic/gin"
+	"net/http"
+)
+
+// APIKeyHandler — управление API-ключами сервисных клиентов.
+type APIKeyHandler struct {
+	svc *service.APIKeyService
+}
+
+func NewAPIKeyHandler(svc *service.APIKeyService) *APIKeyHandler {
+	return &APIKeyHandler{svc: svc}
+}
+
+func (h *APIKeyHandler) RegisterRoutes(r gin.IRouter) {
+	keys := r.Group("/admin/api-keys", RequirePermission(auth.PermAdmin))
+	{
+		keys.POST("/", h.Mint)
+		keys.GET("/", h.List)
+		keys.DELETE("/:id", h.Revoke)
+	}
+}
+
+// Mint выпустить API-ключ
+// @Summary Выпустить API-ключ
+// @Description Ключ возвращается в поле key только в этом ответе; сервис хранит лишь его хеш.
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Param key body models.NewAPIKey true "Имя, области доступа и срок действия"
+// @Success 201 {object} models.IssuedAPIKey
+// @Failure 400 {object} api.Problem "Ошибка разбора тела запроса"
+// @Failure 401 {object} api.Problem "Нет или недействительный токен"
+// @Failure 403 {object} api.Problem "Нет права admin"
+// @Failure 422 {object} api.Problem "Ошибки проверки полей"
+// @Failure 429 {object} api.Problem "Превышен лимит запросов"
+// @Failure 500 {object} api.Problem "Внутренняя ошибка сервера"
+// @Security BearerAuth
+// @Router /admin/api-keys/ [post]
+func (h *APIKeyHandler) Mint(c *gin.Context) {
+	var req models.NewAPIKey
+	if err := c.ShouldBindJSON(&req); err != nil {
+		_ = c.Error(invalidInput("%v", err))
+		return
+	}
+	key, err := h.svc.Mint(c.Request.Context(), req)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+	c.JSON(http.StatusCreated, key)
+}
+
+// List получить список API-ключей
+// @Summary Получить список API-ключей
+// @Description Возвращает все ключи, включая отозванные и просроченные, без их значений.
+// @Tags admin
+// @Produce json
+// @Success 200 {array} models.APIKey
+// @Failure 401 {object} api.Problem "Нет или недействительный токен"
+// @Failure 403 {object} api.Problem "Нет права admin"
+// @Failure 429 {object} api.Problem "Превышен лимит запросов"
+// @Failure 500 {object} api.Problem "Внутренняя ошибка сервера"
+// @Security BearerAuth
+// @Router /admin/api-keys/ [get]
+func (h *APIKeyHandler) List(c *gin.Context) {
+	keys, err := h.svc.List(c.Request.Context())
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+	c.JSON(http.StatusOK, keys)
+}
+
+// Revoke отозвать API-ключ
+// @Summary Отозвать API-ключ
+// @Tags admin
+// @Param id path string true "ID ключа"
+// @Success 204
+// @Failure 400 {object} api.Problem "Некорректный ID"
+// @Failure 401 {object} api.Problem "Нет или недействительный токен"
+// @Failure 403 {object} api.Problem "Нет права admin"
+// @Failure 404 {object} api.Problem "Ключ не найден или уже отозван"
+// @Failure 429 {object} api.Problem "Превышен лимит запросов"
+// @Failure 500 {object} api.Problem "Внутренняя ошибка сервера"
+// @Security BearerAuth
+// @Router /admin/api-keys/{id} [delete]
+func (h *APIKeyHandler) Revoke(c *gin.Context) {
+	if err := h.svc.Revoke(c.Request.Context(), c.Param("id")); err != nil {
+		_ = c.Error(err)
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
@@ -0,0 +1,160 @@
+package api
+
+import (
+	"context"
+	"github.com/Tommych123/subscription-service/models"
+	"github.com/Tommych123/subscription-service/repository"
+	"github.com/Tommych123/subscription-service/service"
+	"github.com/Tommych123/subscription-service/service/auth"
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+	"go.uber.org/zap"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+const (
+	testSecret = "test-secret"
+	alice      = "11111111-1111-1111-1111-111111111111"
+)
+
+func bearer(t *testing.T, secret string, claims jwt.MapClaims) string {
+	t.Helper()
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString([]byte(secret))
+	if err != nil {
+		t.Fatalf("sign token: %v", err)
+	}
+	return "Bearer " + token
+}
+
+func mintKey(t *testing.T, keys *service.APIKeyService, scopes ...string) *models.IssuedAPIKey {
+	t.Helper()
+	issued, err := keys.Mint(context.Background(), models.NewAPIKey{Name: "test", Scopes: scopes})
+	if err != nil {
+		t.Fatalf("Mint() error: %v", err)
+	}
+	return issued
+}
+
+// newAuthRouter возвращает роутер с тестовыми эндпоинтами: /whoami отвечает идентификатором
+// вызывающего, /admin требует право admin, /totals — право totals:read, /write — subscriptions:write.
+// Под /open те же проверки без аутентификации.
+func newAuthRouter(verifier *auth.Verifier, keys *service.APIKeyService) *gin.Engine {
+	r := gin.New()
+	r.Use(ErrorHandler(zap.NewNop()))
+	r.GET("/open/admin", RequirePermission(auth.PermAdmin), func(c *gin.Context) { c.Status(http.StatusNoContent) })
+	r.GET("/open/write", Authorize(auth.PermSubscriptionsWrite), func(c *gin.Context) { c.Status(http.StatusNoContent) })
+	authenticated := r.Group("/", Authenticate(verifier, keys, auth.DefaultPolicy(), zap.NewNop()))
+	authenticated.GET("/whoami", func(c *gin.Context) {
+		p, ok := auth.FromContext(c.Request.Context())
+		if !ok {
+			c.Status(http.StatusInternalServerError)
+			return
+		}
+		c.String(http.StatusOK, p.UserID+p.APIKeyID)
+	})
+	authenticated.GET("/admin", RequirePermission(auth.PermAdmin), func(c *gin.Context) { c.Status(http.StatusNoContent) })
+	authenticated.GET("/totals", Authorize(auth.PermTotalsRead), func(c *gin.Context) { c.Status(http.StatusNoContent) })
+	authenticated.GET("/write", Authorize(auth.PermSubscriptionsWrite), func(c *gin.Context) { c.Status(http.StatusNoContent) })
+	return r
+}
+
+func TestAuthenticate(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	verifier, err := auth.NewVerifier(testSecret, "", "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	keys := service.NewAPIKeyService(repository.NewMemoryAPIKeyRepository(), zap.NewNop())
+	totalsKey := mintKey(t, keys, models.ScopeTotalsRead)
+	readKey := mintKey(t, keys, models.ScopeSubscriptionsRead)
+	revokedKey := mintKey(t, keys, models.ScopeTotalsRead)
+	if err := keys.Revoke(context.Background(), revokedKey.ID); err != nil {
+		t.Fatal(err)
+	}
+	exp := time.Now().Add(time.Hour).Unix()
+	r := newAuthRouter(verifier, keys)
+
+	tests := []struct {
+		name          string
+		path          string
+		authorization string
+		apiKey        string
+		want          int
+		wantCaller    string
+	}{
+		{name: "no header", path: "/whoami", want: http.StatusUnauthorized},
+		{name: "not bearer", path: "/whoami", authorization: "Basic YWxpY2U6cGFzcw==", want: http.StatusUnauthorized},
+		{name: "empty token", path: "/whoami", authorization: "Bearer ", want: http.StatusUnauthorized},
+		{name: "wrong secret", path: "/whoami", authorization: bearer(t, "other", jwt.MapClaims{"sub": alice, "exp": exp}), want: http.StatusUnauthorized},
+		{name: "expired", path: "/whoami", authorization: bearer(t, testSecret, jwt.MapClaims{"sub": alice, "exp": time.Now().Add(-time.Minute).Unix()}), want: http.StatusUnauthorized},
+		{name: "valid", path: "/whoami", authorization: bearer(t, testSecret, jwt.MapClaims{"sub": alice, "exp": exp}), want: http.StatusOK, wantCaller: alice},
+		{name: "admin route without role", path: "/admin", authorization: bearer(t, testSecret, jwt.MapClaims{"sub": alice, "exp": exp}), want: http.StatusForbidden},
+		{name: "admin route with role", path: "/admin", authorization: bearer(t, testSecret, jwt.MapClaims{"sub": alice, "roles": []string{"admin"}, "exp": exp}), want: http.StatusNoContent},
+		{name: "admin route without authentication", path: "/open/admin", want: http.StatusUnauthorized},
+		{name: "api key", path: "/whoami", apiKey: totalsKey.Key, want: http.StatusOK, wantCaller: totalsKey.ID},
+		{name: "api key wins over bearer", path: "/whoami", apiKey: totalsKey.Key, authorization: "Bearer garbage", want: http.StatusOK, wantCaller: totalsKey.ID},
+		{name: "unknown api key", path: "/whoami", apiKey: "sk_unknown", want: http.StatusUnauthorized},
+		{name: "revoked api key", path: "/whoami", apiKey: revokedKey.Key, want: http.StatusUnauthorized},
+		{name: "api key with scope", path: "/totals", apiKey: totalsKey.Key, want: http.StatusNoContent},
+		{name: "api key without scope", path: "/totals", apiKey: readKey.Key, want: http.StatusForbidden},
+		{name: "user without roles reads", path: "/totals", authorization: bearer(t, testSecret, jwt.MapClaims{"sub": alice, "exp": exp}), want: http.StatusNoContent},
+		{name: "user without roles cannot write", path: "/write", authorization: bearer(t, testSecret, jwt.MapClaims{"sub": alice, "exp": exp}), want: http.StatusForbidden},
+		{name: "editor writes", path: "/write", authorization: bearer(t, testSecret, jwt.MapClaims{"sub": alice, "roles": []string{"editor"}, "exp": exp}), want: http.StatusNoContent},
+		{name: "unknown role grants nothing", path: "/totals", authorization: bearer(t, testSecret, jwt.MapClaims{"sub": alice, "roles": []string{"owner"}, "exp": exp}), want: http.StatusForbidden},
+		{name: "editor route without admin", path: "/admin", authorization: bearer(t, testSecret, jwt.MapClaims{"sub": alice, "roles": []string{"editor"}, "exp": exp}), want: http.StatusForbidden},
+		{name: "write without authentication", path: "/open/write", want: http.StatusNoContent},
+		{name: "admin route with api key", path: "/admin", apiKey: totalsKey.Key, want: http.StatusForbidden},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, tt.path, nil)
+			if tt.authorization != "" {
+				req.Header.Set("Authorization", tt.authorization)
+			}
+			if tt.apiKey != "" {
+				req.Header.Set(apiKeyHeader, tt.apiKey)
+			}
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+			if w.Code != tt.want {
+				t.Fatalf("status = %d, want %d: %s", w.Code, tt.want, w.Body)
+			}
+			switch w.Code {
+			case http.StatusOK:
+				if w.Body.String() != tt.wantCaller {
+					t.Errorf("principal = %q, want %q", w.Body.String(), tt.wantCaller)
+				}
+			case http.StatusUnauthorized:
+				if w.Header().Get("WWW-Authenticate") == "" {
+					t.Error("401 without WWW-Authenticate header")
+				}
+			}
+		})
+	}
+}
+
+func TestAuthenticateWithoutJWT(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	keys := service.NewAPIKeyService(repository.NewMemoryAPIKeyRepository(), zap.NewNop())
+	key := mintKey(t, keys, models.ScopeTotalsRead)
+	r := newAuthRouter(nil, keys)
+
+	req := httptest.NewRequest(http.MethodGet, "/whoami", nil)
+	req.Header.Set("Authorization", bearer(t, testSecret, jwt.MapClaims{"sub": alice, "exp": time.Now().Add(time.Hour).Unix()}))
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	if w.Code != http.StatusUnauthorized {
+		t.Errorf("bearer without verifier: status = %d, want 401", w.Code)
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/whoami", nil)
+	req.Header.Set(apiKeyHeader, key.Key)
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	if w.Code != http.StatusOK || w.Body.String() != key.ID {
+		t.Errorf("api key without verifier: status = %d, body = %q, want 200 %q", w.Code, w.Body, key.ID)
+	}
+}
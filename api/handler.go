@@ -4,31 +4,38 @@ import (
 	_ "github.com/Tommych123/subscription-service/internal/docs"
 	"github.com/Tommych123/subscription-service/models"
 	"github.com/Tommych123/subscription-service/service"
+	"github.com/Tommych123/subscription-service/service/auth"
 	"github.com/gin-gonic/gin"
-	"go.uber.org/zap"
 	"net/http"
+	"strconv"
+	"strings"
 	"time"
 )
 
 type SubscriptionHandler struct {
-	svc    *service.SubscriptionService
-	logger *zap.Logger
+	svc *service.SubscriptionService
 }
 
-func NewSubscriptionHandler(svc *service.SubscriptionService, logger *zap.Logger) *SubscriptionHandler {
-	return &SubscriptionHandler{svc: svc, logger: logger}
+func NewSubscriptionHandler(svc *service.SubscriptionService) *SubscriptionHandler {
+	return &SubscriptionHandler{svc: svc}
 }
 
-func (h *SubscriptionHandler) RegisterRoutes(r *gin.Engine) {
+func (h *SubscriptionHandler) RegisterRoutes(r gin.IRouter) {
+	read := Authorize(auth.PermSubscriptionsRead)
+	write := Authorize(auth.PermSubscriptionsWrite)
+	totals := Authorize(auth.PermTotalsRead)
 	sub := r.Group("/subscriptions")
 	{
-		sub.POST("/", h.Create)
-		sub.GET("/", h.List)
-		sub.GET("/:id", h.GetByID)
-		sub.PUT("/:id", h.Update)
-		sub.DELETE("/:id", h.Delete)
+		sub.POST("/", write, h.Create)
+		sub.GET("/", read, h.List)
+		sub.GET("/:id", read, h.GetByID)
+		sub.PUT("/:id", write, h.Update)
+		sub.PATCH("/:id", write, h.Patch)
+		sub.PUT("/:id/price", write, h.SchedulePrice)
+		sub.DELETE("/:id", write, h.Delete)
 	}
-	r.GET("/total", h.GetTotalCost)
+	r.GET("/total", totals, h.GetTotalCost)
+	r.GET("/total/breakdown", totals, h.GetCostBreakdown)
 }
 
 // Create подписку
@@ -36,25 +43,30 @@ func (h *SubscriptionHandler) RegisterRoutes(r *gin.Engine) {
 // @Tags subscriptions
 // @Accept json
 // @Produce json
-// @Param subscription body model.Subscription true "Подписка"
+// @Param subscription body models.Subscription true "Подписка"
 // @Success 201 {object} map[string]string "id новой подписки"
-// @Failure 400 {object} map[string]string "Ошибка валидации входных данных"
-// @Failure 500 {object} map[string]string "Внутренняя ошибка сервера"
+// @Failure 400 {object} api.Problem "Ошибка валидации входных данных"
+// @Failure 401 {object} api.Problem "Нет или недействительный токен или API-ключ"
+// @Failure 403 {object} api.Problem "Роль или API-ключ не дают нужного права либо нет доступа к данным другого пользователя"
+// @Failure 409 {object} api.Problem "Конфликт с существующими данными"
+// @Failure 422 {object} api.Problem "Ошибки проверки полей"
+// @Failure 429 {object} api.Problem "Превышен лимит запросов"
+// @Failure 500 {object} api.Problem "Внутренняя ошибка сервера"
+// @Failure 504 {object} api.Problem "Превышено время обработки запроса"
+// @Security BearerAuth
+// @Security ApiKeyAuth
 // @Router /subscriptions/ [post]
 func (h *SubscriptionHandler) Create(c *gin.Context) {
 	var sub models.Subscription
 	if err := c.ShouldBindJSON(&sub); err != nil {
-		h.logger.Warn("Invalid input for Create", zap.Error(err))
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input: " + err.Error()})
+		_ = c.Error(invalidInput("%v", err))
 		return
 	}
-	id, err := h.svc.Create(&sub)
+	id, err := h.svc.Create(c.Request.Context(), &sub)
 	if err != nil {
-		h.logger.Error("Failed to create subscription", zap.Error(err))
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create: " + err.Error()})
+		_ = c.Error(err)
 		return
 	}
-	h.logger.Info("Subscription created", zap.String("id", id))
 	c.JSON(http.StatusCreated, gin.H{"id": id})
 }
 
@@ -63,21 +75,22 @@ func (h *SubscriptionHandler) Create(c *gin.Context) {
 // @Tags subscriptions
 // @Produce json
 // @Param id path string true "ID подписки"
-// @Success 200 {object} model.Subscription
-// @Failure 404 {object} map[string]string "Подписка не найдена"
-// @Failure 500 {object} map[string]string "Внутренняя ошибка сервера"
+// @Success 200 {object} models.Subscription
+// @Failure 400 {object} api.Problem "Некорректный ID"
+// @Failure 401 {object} api.Problem "Нет или недействительный токен или API-ключ"
+// @Failure 403 {object} api.Problem "Роль или API-ключ не дают нужного права либо нет доступа к данным другого пользователя"
+// @Failure 404 {object} api.Problem "Подписка не найдена"
+// @Failure 429 {object} api.Problem "Превышен лимит запросов"
+// @Failure 500 {object} api.Problem "Внутренняя ошибка сервера"
+// @Failure 504 {object} api.Problem "Превышено время обработки запроса"
+// @Security BearerAuth
+// @Security ApiKeyAuth
 // @Router /subscriptions/{id} [get]
 func (h *SubscriptionHandler) GetByID(c *gin.Context) {
 	id := c.Param("id")
-	sub, err := h.svc.GetByID(id)
+	sub, err := h.svc.GetByID(c.Request.Context(), id)
 	if err != nil {
-		h.logger.Error("Failed to get subscription by ID", zap.Error(err), zap.String("id", id))
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
-		return
-	}
-	if sub == nil {
-		h.logger.Info("Subscription not found", zap.String("id", id))
-		c.JSON(http.StatusNotFound, gin.H{"error": "Subscription not found"})
+		_ = c.Error(err)
 		return
 	}
 	c.JSON(http.StatusOK, sub)
@@ -85,63 +98,171 @@ func (h *SubscriptionHandler) GetByID(c *gin.Context) {
 
 // Update обновить подписку
 // @Summary Обновить подписку
+// @Description Цена должна совпадать с текущей; новая цена назначается через PUT /subscriptions/{id}/price.
 // @Tags subscriptions
 // @Accept json
 // @Produce json
 // @Param id path string true "ID подписки"
-// @Param subscription body model.Subscription true "Подписка"
+// @Param subscription body models.Subscription true "Подписка"
 // @Success 200 "Обновление успешно"
-// @Failure 400 {object} map[string]string "Ошибка валидации входных данных"
-// @Failure 500 {object} map[string]string "Внутренняя ошибка сервера"
+// @Failure 400 {object} api.Problem "Ошибка валидации входных данных"
+// @Failure 401 {object} api.Problem "Нет или недействительный токен или API-ключ"
+// @Failure 403 {object} api.Problem "Роль или API-ключ не дают нужного права либо нет доступа к данным другого пользователя"
+// @Failure 404 {object} api.Problem "Подписка не найдена"
+// @Failure 409 {object} api.Problem "Конфликт с существующими данными"
+// @Failure 422 {object} api.Problem "Ошибки проверки полей, в том числе цена отличается от текущей"
+// @Failure 429 {object} api.Problem "Превышен лимит запросов"
+// @Failure 500 {object} api.Problem "Внутренняя ошибка сервера"
+// @Failure 504 {object} api.Problem "Превышено время обработки запроса"
+// @Security BearerAuth
+// @Security ApiKeyAuth
 // @Router /subscriptions/{id} [put]
 func (h *SubscriptionHandler) Update(c *gin.Context) {
 	id := c.Param("id")
 	var sub models.Subscription
 	if err := c.ShouldBindJSON(&sub); err != nil {
-		h.logger.Warn("Invalid input for Update", zap.Error(err))
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		_ = c.Error(invalidInput("%v", err))
 		return
 	}
 	sub.ID = id
-	if err := h.svc.Update(&sub); err != nil {
-		h.logger.Error("Failed to update subscription", zap.Error(err), zap.String("id", id))
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	if err := h.svc.Update(c.Request.Context(), &sub); err != nil {
+		_ = c.Error(err)
 		return
 	}
-	h.logger.Info("Subscription updated", zap.String("id", id))
 	c.Status(http.StatusOK)
 }
 
+// Patch частично обновить подписку
+// @Summary Частично обновить подписку (JSON Merge Patch)
+// @Description Передаются только изменяемые поля; null в end_date делает подписку бессрочной. Цену так изменить нельзя.
+// @Tags subscriptions
+// @Accept json
+// @Accept application/merge-patch+json
+// @Produce json
+// @Param id path string true "ID подписки"
+// @Param patch body models.SubscriptionPatch true "Изменяемые поля подписки"
+// @Success 200 {object} models.Subscription
+// @Failure 400 {object} api.Problem "Ошибка валидации входных данных"
+// @Failure 401 {object} api.Problem "Нет или недействительный токен или API-ключ"
+// @Failure 403 {object} api.Problem "Роль или API-ключ не дают нужного права либо нет доступа к данным другого пользователя"
+// @Failure 404 {object} api.Problem "Подписка не найдена"
+// @Failure 409 {object} api.Problem "Конфликт с существующими данными"
+// @Failure 422 {object} api.Problem "Ошибки проверки полей, в том числе цена отличается от текущей"
+// @Failure 429 {object} api.Problem "Превышен лимит запросов"
+// @Failure 500 {object} api.Problem "Внутренняя ошибка сервера"
+// @Failure 504 {object} api.Problem "Превышено время обработки запроса"
+// @Security BearerAuth
+// @Security ApiKeyAuth
+// @Router /subscriptions/{id} [patch]
+func (h *SubscriptionHandler) Patch(c *gin.Context) {
+	id := c.Param("id")
+	var patch models.SubscriptionPatch
+	if err := c.ShouldBindJSON(&patch); err != nil {
+		_ = c.Error(invalidInput("%v", err))
+		return
+	}
+	sub, err := h.svc.Patch(c.Request.Context(), id, &patch)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+	c.JSON(http.StatusOK, sub)
+}
+
+// SchedulePrice назначить новую цену подписки
+// @Summary Назначить новую цену подписки с указанного месяца
+// @Description Цена действует с месяца effective_from (не раньше текущего) до следующего изменения; итоги за прошлые месяцы не меняются.
+// @Tags subscriptions
+// @Accept json
+// @Param id path string true "ID подписки"
+// @Param price body models.PriceChange true "Новая цена"
+// @Success 204 "Цена назначена"
+// @Failure 400 {object} api.Problem "Ошибка валидации входных данных"
+// @Failure 401 {object} api.Problem "Нет или недействительный токен или API-ключ"
+// @Failure 403 {object} api.Problem "Роль или API-ключ не дают нужного права либо нет доступа к данным другого пользователя"
+// @Failure 404 {object} api.Problem "Подписка не найдена"
+// @Failure 422 {object} api.Problem "Ошибки проверки полей"
+// @Failure 429 {object} api.Problem "Превышен лимит запросов"
+// @Failure 500 {object} api.Problem "Внутренняя ошибка сервера"
+// @Failure 504 {object} api.Problem "Превышено время обработки запроса"
+// @Security BearerAuth
+// @Security ApiKeyAuth
+// @Router /subscriptions/{id}/price [put]
+func (h *SubscriptionHandler) SchedulePrice(c *gin.Context) {
+	id := c.Param("id")
+	var change models.PriceChange
+	if err := c.ShouldBindJSON(&change); err != nil {
+		_ = c.Error(invalidInput("%v", err))
+		return
+	}
+	if err := h.svc.SchedulePrice(c.Request.Context(), id, change); err != nil {
+		_ = c.Error(err)
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
 // Delete удалить подписку
 // @Summary Удалить подписку
 // @Tags subscriptions
 // @Param id path string true "ID подписки"
 // @Success 204 "Удаление успешно"
-// @Failure 500 {object} map[string]string "Внутренняя ошибка сервера"
+// @Failure 400 {object} api.Problem "Некорректный ID"
+// @Failure 401 {object} api.Problem "Нет или недействительный токен или API-ключ"
+// @Failure 403 {object} api.Problem "Роль или API-ключ не дают нужного права либо нет доступа к данным другого пользователя"
+// @Failure 404 {object} api.Problem "Подписка не найдена"
+// @Failure 429 {object} api.Problem "Превышен лимит запросов"
+// @Failure 500 {object} api.Problem "Внутренняя ошибка сервера"
+// @Failure 504 {object} api.Problem "Превышено время обработки запроса"
+// @Security BearerAuth
+// @Security ApiKeyAuth
 // @Router /subscriptions/{id} [delete]
 func (h *SubscriptionHandler) Delete(c *gin.Context) {
 	id := c.Param("id")
-	if err := h.svc.Delete(id); err != nil {
-		h.logger.Error("Failed to delete subscription", zap.Error(err), zap.String("id", id))
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	if err := h.svc.Delete(c.Request.Context(), id); err != nil {
+		_ = c.Error(err)
 		return
 	}
-	h.logger.Info("Subscription deleted", zap.String("id", id))
 	c.Status(http.StatusNoContent)
 }
 
 // List получить список подписок
-// @Summary Получить список всех подписок
+// @Summary Получить список подписок с фильтрацией, сортировкой и пагинацией
 // @Tags subscriptions
 // @Produce json
-// @Success 200 {array} model.Subscription
-// @Failure 500 {object} map[string]string "Внутренняя ошибка сервера"
+// @Param limit query int false "Размер страницы (по умолчанию 50, максимум 1000)"
+// @Param offset query int false "Смещение от начала выборки"
+// @Param cursor query string false "Курсор следующей страницы (next_cursor из предыдущего ответа)"
+// @Param user_id query string false "ID пользователя (UUID)"
+// @Param service_name query string false "Точное название сервиса"
+// @Param service_name_prefix query string false "Префикс названия сервиса"
+// @Param price_min query number false "Минимальная стоимость"
+// @Param price_max query number false "Максимальная стоимость"
+// @Param active_at query string false "Подписка активна в месяце (MM-YYYY)" example(07-2025)
+// @Param start_from query string false "Дата начала не раньше (MM-YYYY)"
+// @Param start_to query string false "Дата начала не позже (MM-YYYY)"
+// @Param end_from query string false "Дата окончания не раньше (MM-YYYY)"
+// @Param end_to query string false "Дата окончания не позже (MM-YYYY)"
+// @Param sort query string false "Поле сортировки, префикс '-' для сортировки по убыванию" example(-start_date)
+// @Success 200 {object} models.SubscriptionList
+// @Failure 400 {object} api.Problem "Ошибка валидации входных данных"
+// @Failure 401 {object} api.Problem "Нет или недействительный токен или API-ключ"
+// @Failure 403 {object} api.Problem "Роль или API-ключ не дают нужного права либо нет доступа к данным другого пользователя"
+// @Failure 429 {object} api.Problem "Превышен лимит запросов"
+// @Failure 500 {object} api.Problem "Внутренняя ошибка сервера"
+// @Failure 504 {object} api.Problem "Превышено время обработки запроса"
+// @Security BearerAuth
+// @Security ApiKeyAuth
 // @Router /subscriptions/ [get]
 func (h *SubscriptionHandler) List(c *gin.Context) {
-	subs, err := h.svc.List()
+	params, err := parseListParams(c)
 	if err != nil {
-		h.logger.Error("Failed to list subscriptions", zap.Error(err))
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		_ = c.Error(err)
+		return
+	}
+	subs, err := h.svc.List(c.Request.Context(), params)
+	if err != nil {
+		_ = c.Error(err)
 		return
 	}
 	c.JSON(http.StatusOK, subs)
@@ -155,40 +276,198 @@ func (h *SubscriptionHandler) List(c *gin.Context) {
 // @Param service_name query string false "Название сервиса"
 // @Param from query string true "Дата начала периода (MM-YYYY)" example(01-2023)
 // @Param to query string true "Дата окончания периода (MM-YYYY)" example(12-2023)
-// @Success 200 {object} map[string]int "Суммарная стоимость"
-// @Failure 400 {object} map[string]string "Ошибка валидации входных данных"
-// @Failure 500 {object} map[string]string "Внутренняя ошибка сервера"
+// @Param currency query string false "Валюта итога (ISO 4217), по умолчанию RUB" example(USD)
+// @Success 200 {object} map[string]interface{} "Суммарная стоимость и валюта"
+// @Failure 400 {object} api.Problem "Ошибка валидации входных данных"
+// @Failure 401 {object} api.Problem "Нет или недействительный токен или API-ключ"
+// @Failure 403 {object} api.Problem "Роль или API-ключ не дают нужного права либо нет доступа к данным другого пользователя"
+// @Failure 422 {object} api.Problem "Период from..to задан неверно, итог вне допустимого диапазона или нет курса для пересчёта валюты"
+// @Failure 429 {object} api.Problem "Превышен лимит запросов"
+// @Failure 500 {object} api.Problem "Внутренняя ошибка сервера"
+// @Failure 504 {object} api.Problem "Превышено время обработки запроса"
+// @Security BearerAuth
+// @Security ApiKeyAuth
 // @Router /total [get]
 func (h *SubscriptionHandler) GetTotalCost(c *gin.Context) {
 	userID := c.Query("user_id")
 	serviceName := c.Query("service_name")
-	fromStr := c.Query("from")
-	toStr := c.Query("to")
+	from, to, err := parsePeriod(c)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+	currency, err := parseCurrency(c)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
 
-	from, err := parseMonthYear(fromStr)
+	total, err := h.svc.GetTotalCost(c.Request.Context(), userID, serviceName, from, to, currency)
 	if err != nil {
-		h.logger.Warn("Invalid from date format", zap.String("from", fromStr), zap.Error(err))
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid from date format"})
+		_ = c.Error(err)
 		return
 	}
-	to, err := parseMonthYear(toStr)
+
+	c.JSON(http.StatusOK, gin.H{"total_cost": total, "currency": currency})
+}
+
+// GetCostBreakdown вычислить помесячную разбивку стоимости подписок
+// @Summary Получить стоимость подписок по месяцам за период
+// @Tags subscriptions
+// @Produce json
+// @Param user_id query string false "ID пользователя (UUID)"
+// @Param service_name query string false "Название сервиса"
+// @Param from query string true "Дата начала периода (MM-YYYY)" example(01-2023)
+// @Param to query string true "Дата окончания периода (MM-YYYY)" example(12-2023)
+// @Param group_by query string false "Группировка внутри месяца" Enums(service_name, user_id)
+// @Param currency query string false "Валюта итога (ISO 4217), по умолчанию RUB" example(USD)
+// @Success 200 {object} models.CostBreakdown
+// @Failure 400 {object} api.Problem "Ошибка валидации входных данных"
+// @Failure 401 {object} api.Problem "Нет или недействительный токен или API-ключ"
+// @Failure 403 {object} api.Problem "Роль или API-ключ не дают нужного права либо нет доступа к данным другого пользователя"
+// @Failure 422 {object} api.Problem "Период from..to задан неверно, итог вне допустимого диапазона или нет курса для пересчёта валюты"
+// @Failure 429 {object} api.Problem "Превышен лимит запросов"
+// @Failure 500 {object} api.Problem "Внутренняя ошибка сервера"
+// @Failure 504 {object} api.Problem "Превышено время обработки запроса"
+// @Security BearerAuth
+// @Security ApiKeyAuth
+// @Router /total/breakdown [get]
+func (h *SubscriptionHandler) GetCostBreakdown(c *gin.Context) {
+	userID := c.Query("user_id")
+	serviceName := c.Query("service_name")
+	groupBy := c.Query("group_by")
+	if groupBy != "" && !models.CostGroupByFields[groupBy] {
+		_ = c.Error(invalidInput("group_by must be service_name or user_id"))
+		return
+	}
+	from, to, err := parsePeriod(c)
 	if err != nil {
-		h.logger.Warn("Invalid to date format", zap.String("to", toStr), zap.Error(err))
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid to date format"})
+		_ = c.Error(err)
+		return
+	}
+	currency, err := parseCurrency(c)
+	if err != nil {
+		_ = c.Error(err)
 		return
 	}
 
-	total, err := h.svc.GetTotalCost(userID, serviceName, from, to)
+	breakdown, err := h.svc.GetCostBreakdown(c.Request.Context(), userID, serviceName, from, to, groupBy, currency)
 	if err != nil {
-		h.logger.Error("Failed to get total cost", zap.Error(err))
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		_ = c.Error(err)
 		return
 	}
+	c.JSON(http.StatusOK, breakdown)
+}
 
-	h.logger.Info("Total cost calculated", zap.String("user_id", userID), zap.String("service_name", serviceName), zap.Int("total_cost", total))
-	c.JSON(http.StatusOK, gin.H{"total_cost": total})
+// parsePeriod разбирает обязательные параметры from и to в формате MM-YYYY.
+func parsePeriod(c *gin.Context) (time.Time, time.Time, error) {
+	from, err := parseMonthYear(c.Query("from"))
+	if err != nil {
+		return time.Time{}, time.Time{}, invalidInput("invalid from date format, expected MM-YYYY")
+	}
+	to, err := parseMonthYear(c.Query("to"))
+	if err != nil {
+		return time.Time{}, time.Time{}, invalidInput("invalid to date format, expected MM-YYYY")
+	}
+	return from, to, nil
+}
+
+// parseCurrency разбирает необязательный параметр currency (по умолчанию RUB).
+func parseCurrency(c *gin.Context) (models.Currency, error) {
+	currencyStr := c.Query("currency")
+	if currencyStr == "" {
+		return models.DefaultCurrency, nil
+	}
+	currency, err := models.ParseCurrency(currencyStr)
+	if err != nil {
+		return "", invalidInput("%v", err)
+	}
+	return currency, nil
 }
 
 func parseMonthYear(s string) (time.Time, error) {
 	return time.Parse("01-2006", s)
 }
+
+const (
+	defaultListLimit = 50
+	maxListLimit     = 1000
+)
+
+func parseListParams(c *gin.Context) (models.ListParams, error) {
+	params := models.ListParams{
+		Limit:             defaultListLimit,
+		UserID:            c.Query("user_id"),
+		ServiceName:       c.Query("service_name"),
+		ServiceNamePrefix: c.Query("service_name_prefix"),
+	}
+	var err error
+	if v := c.Query("limit"); v != "" {
+		if params.Limit, err = strconv.Atoi(v); err != nil || params.Limit < 1 || params.Limit > maxListLimit {
+			return params, invalidInput("limit must be an integer between 1 and %d", maxListLimit)
+		}
+	}
+	if v := c.Query("offset"); v != "" {
+		if params.Offset, err = strconv.Atoi(v); err != nil || params.Offset < 0 {
+			return params, invalidInput("offset must be a non-negative integer")
+		}
+	}
+	if v := c.Query("cursor"); v != "" {
+		if params.Offset, err = service.DecodeCursor(v); err != nil {
+			return params, invalidInput("invalid cursor")
+		}
+	}
+	if params.PriceMin, err = parseOptionalMoney(c, "price_min"); err != nil {
+		return params, err
+	}
+	if params.PriceMax, err = parseOptionalMoney(c, "price_max"); err != nil {
+		return params, err
+	}
+	dates := []struct {
+		key  string
+		dest **time.Time
+	}{
+		{"active_at", &params.ActiveAt},
+		{"start_from", &params.StartFrom},
+		{"start_to", &params.StartTo},
+		{"end_from", &params.EndFrom},
+		{"end_to", &params.EndTo},
+	}
+	for _, d := range dates {
+		if *d.dest, err = parseOptionalMonthYear(c, d.key); err != nil {
+			return params, err
+		}
+	}
+	if sort := c.Query("sort"); sort != "" {
+		params.Desc = strings.HasPrefix(sort, "-")
+		params.Sort = strings.TrimPrefix(sort, "-")
+		if !models.SubscriptionSortFields[params.Sort] {
+			return params, invalidInput("unsupported sort field %q", params.Sort)
+		}
+	}
+	return params, nil
+}
+
+func parseOptionalMoney(c *gin.Context, key string) (*models.Money, error) {
+	v := c.Query(key)
+	if v == "" {
+		return nil, nil
+	}
+	m, err := models.ParseMoney(v)
+	if err != nil {
+		return nil, invalidInput("%s must be a decimal amount with at most 2 decimal places", key)
+	}
+	return &m, nil
+}
+
+func parseOptionalMonthYear(c *gin.Context, key string) (*time.Time, error) {
+	v := c.Query(key)
+	if v == "" {
+		return nil, nil
+	}
+	t, err := parseMonthYear(v)
+	if err != nil {
+		return nil, invalidInput("invalid %s date format, expected MM-YYYY", key)
+	}
+	return &t, nil
+}
@@ -0,0 +1,100 @@
+package api
+
+import (
+	"context"
+	"github.com/gin-gonic/gin"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// readinessTimeout ограничивает время выполнения каждой проверки готовности.
+const readinessTimeout = 3 * time.Second
+
+// HealthCheck — проверка зависимости сервиса для /readyz.
+type HealthCheck struct {
+	Name  string
+	Check func(ctx context.Context) error
+}
+
+// CheckResult — результат одной проверки готовности.
+type CheckResult struct {
+	Status    string  `json:"status" example:"ok"`
+	LatencyMs float64 `json:"latency_ms" example:"1.25"`
+	Error     string  `json:"error,omitempty"`
+}
+
+// HealthStatus — ответ /healthz и /readyz.
+type HealthStatus struct {
+	Status string                 `json:"status" example:"ok"`
+	Checks map[string]CheckResult `json:"checks,omitempty"`
+}
+
+type HealthHandler struct {
+	checks []HealthCheck
+}
+
+func NewHealthHandler(checks ...HealthCheck) *HealthHandler {
+	return &HealthHandler{checks: checks}
+}
+
+func (h *HealthHandler) RegisterRoutes(r *gin.Engine) {
+	r.GET("/healthz", h.Liveness)
+	r.GET("/readyz", h.Readiness)
+}
+
+// Liveness сообщает, что процесс запущен
+// @Summary Проверка живости
+// @Tags health
+// @Produce json
+// @Success 200 {object} api.HealthStatus
+// @Router /healthz [get]
+func (h *HealthHandler) Liveness(c *gin.Context) {
+	c.JSON(http.StatusOK, HealthStatus{Status: "ok"})
+}
+
+// Readiness выполняет проверки зависимостей
+// @Summary Проверка готовности
+// @Description Проверяет доступность PostgreSQL и версию миграций. Возвращает 503, если хотя бы одна проверка не прошла.
+// @Tags health
+// @Produce json
+// @Success 200 {object} api.HealthStatus
+// @Failure 503 {object} api.HealthStatus
+// @Router /readyz [get]
+func (h *HealthHandler) Readiness(c *gin.Context) {
+	resp := HealthStatus{Status: "ok", Checks: make(map[string]CheckResult, len(h.checks))}
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	for _, check := range h.checks {
+		wg.Add(1)
+		go func(check HealthCheck) {
+			defer wg.Done()
+			result := runCheck(c.Request.Context(), check)
+			mu.Lock()
+			defer mu.Unlock()
+			resp.Checks[check.Name] = result
+			if result.Status != "ok" {
+				resp.Status = "fail"
+			}
+		}(check)
+	}
+	wg.Wait()
+	status := http.StatusOK
+	if resp.Status != "ok" {
+		status = http.StatusServiceUnavailable
+	}
+	c.JSON(status, resp)
+}
+
+func runCheck(ctx context.Context, check HealthCheck) CheckResult {
+	ctx, cancel := context.WithTimeout(ctx, readinessTimeout)
+	defer cancel()
+	start := time.Now()
+	err := check.Check(ctx)
+	result := CheckResult{Status: "ok", LatencyMs: float64(time.Since(start).Microseconds()) / 1000}
+	if err != nil {
+		result.Status = "fail"
+		result.Error = err.Error()
+	}
+	return result
+}
@@ -0,0 +1,29 @@
+package api
+
+import (
+	"github.com/Tommych123/subscription-service/internal/metrics"
+	"github.com/gin-gonic/gin"
+	"strconv"
+	"time"
+)
+
+// Metrics учитывает количество и длительность запросов по шаблону маршрута и итоговому статусу.
+// Подключается сразу после Recovery, чтобы видеть статус, выставленный ErrorHandler.
+func Metrics() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+		c.Next()
+		route := routeName(c)
+		status := strconv.Itoa(c.Writer.Status())
+		metrics.HTTPRequests.WithLabelValues(c.Request.Method, route, status).Inc()
+		metrics.HTTPRequestDuration.WithLabelValues(c.Request.Method, route, status).Observe(time.Since(start).Seconds())
+	}
+}
+
+// routeName возвращает шаблон маршрута запроса или "unmatched", если маршрут не найден.
+func routeName(c *gin.Context) string {
+	if route := c.FullPath(); route != "" {
+		return route
+	}
+	return "unmatched"
+}
@@ -0,0 +1,113 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/Tommych123/subscription-service/internal/logger"
+	"github.com/Tommych123/subscription-service/service/errs"
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+	"net/http"
+)
+
+const problemContentType = "application/problem+json"
+
+// statusClientClosedRequest — нестандартный код 499 (как в nginx) для запросов, отменённых клиентом.
+const statusClientClosedRequest = 499
+
+// Problem — описание ошибки в формате RFC 7807 (application/problem+json).
+type Problem struct {
+	Type     string            `json:"type" example:"/problems/not-found"`
+	Title    string            `json:"title" example:"Not Found"`
+	Status   int               `json:"status" example:"404"`
+	Detail   string            `json:"detail,omitempty" example:"subscription 550e8400-e29b-41d4-a716-446655440000: not found"`
+	Instance string            `json:"instance,omitempty" example:"/subscriptions/550e8400-e29b-41d4-a716-446655440000"`
+	Errors   []errs.FieldError `json:"errors,omitempty"`
+}
+
+// ErrorHandler превращает последнюю ошибку, переданную обработчиком через c.Error,
+// в ответ application/problem+json. Текст внутренних ошибок клиенту не отдаётся.
+// Ошибка пишется в логгер запроса, а если его нет — в base; сервис ошибки, возвращаемые
+// клиенту, сам не логирует, поэтому каждая из них попадает в лог ровно один раз.
+func ErrorHandler(base *zap.Logger) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Next()
+		if len(c.Errors) == 0 || c.Writer.Written() {
+			return
+		}
+		err := c.Errors.Last().Err
+		problem := newProblem(err)
+		log := logger.FromContext(c.Request.Context(), base)
+		fields := []zap.Field{
+			zap.Int("status", problem.Status),
+			zap.Error(err),
+		}
+		if problem.Status >= http.StatusInternalServerError {
+			log.Error("Request failed", fields...)
+		} else {
+			log.Warn("Request rejected", fields...)
+		}
+		if problem.Status == http.StatusUnauthorized {
+			c.Header("WWW-Authenticate", `Bearer realm="subscription-service"`)
+		}
+		writeProblem(c, problem)
+	}
+}
+
+// writeProblem отправляет problem клиенту, указав в Instance адрес запроса.
+func writeProblem(c *gin.Context, problem Problem) {
+	problem.Instance = c.Request.URL.RequestURI()
+	c.Header("Content-Type", problemContentType)
+	c.AbortWithStatusJSON(problem.Status, problem)
+}
+
+// NoRoute отвечает 404 в формате problem+json на запросы к неизвестным путям.
+func NoRoute(c *gin.Context) {
+	_ = c.Error(fmt.Errorf("route %s %s: %w", c.Request.Method, c.Request.URL.Path, errs.ErrNotFound))
+}
+
+func newProblem(err error) Problem {
+	var verr *errs.ValidationError
+	switch {
+	case errors.As(err, &verr):
+		return Problem{
+			Type:   "/problems/validation-error",
+			Title:  "Validation Failed",
+			Status: http.StatusUnprocessableEntity,
+			Detail: "One or more fields are invalid",
+			Errors: verr.Fields,
+		}
+	case errors.Is(err, errs.ErrInvalidInput):
+		return problem("/problems/invalid-input", http.StatusBadRequest, err.Error())
+	case errors.Is(err, errs.ErrInvalidID):
+		return problem("/problems/invalid-id", http.StatusBadRequest, err.Error())
+	case errors.Is(err, errs.ErrUnauthorized):
+		return problem("/problems/unauthorized", http.StatusUnauthorized, "Missing or invalid bearer token or API key")
+	case errors.Is(err, errs.ErrForbidden):
+		return problem("/problems/forbidden", http.StatusForbidden, err.Error())
+	case errors.Is(err, errs.ErrRateLimited):
+		return problem("/problems/rate-limited", http.StatusTooManyRequests, err.Error())
+	case errors.Is(err, errs.ErrNotFound):
+		return problem("/problems/not-found", http.StatusNotFound, err.Error())
+	case errors.Is(err, errs.ErrConflict):
+		return problem("/problems/conflict", http.StatusConflict, "The request conflicts with existing data")
+	case errors.Is(err, errs.ErrRateNotFound):
+		return problem("/problems/exchange-rate-not-found", http.StatusUnprocessableEntity, err.Error())
+	case errors.Is(err, context.DeadlineExceeded):
+		return problem("/problems/timeout", http.StatusGatewayTimeout, "The request took too long to process")
+	case errors.Is(err, context.Canceled):
+		return Problem{Type: "/problems/client-closed-request", Title: "Client Closed Request", Status: statusClientClosedRequest, Detail: "The client closed the connection before the response was ready"}
+	default:
+		return problem("about:blank", http.StatusInternalServerError, "An unexpected error occurred")
+	}
+}
+
+func problem(typ string, status int, detail string) Problem {
+	return Problem{Type: typ, Title: http.StatusText(status), Status: status, Detail: detail}
+}
+
+// invalidInput помечает ошибку разбора запроса как errs.ErrInvalidInput.
+func invalidInput(format string, args ...interface{}) error {
+	return fmt.Errorf("%w: %s", errs.ErrInvalidInput, fmt.Sprintf(format, args...))
+}
@@ -0,0 +1,86 @@
+package api
+
+import (
+	"fmt"
+	"github.com/Tommych123/subscription-service/internal/logger"
+	"github.com/Tommych123/subscription-service/internal/metrics"
+	"github.com/Tommych123/subscription-service/internal/ratelimit"
+	"github.com/Tommych123/subscription-service/service/auth"
+	"github.com/Tommych123/subscription-service/service/errs"
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+	"math"
+	"strconv"
+	"time"
+)
+
+// defaultLimitScope — общее ведро клиента для маршрутов без собственного лимита.
+const defaultLimitScope = "*"
+
+// IPRateLimit ограничивает частоту всех запросов с одного IP. Подключается до Authenticate,
+// чтобы перебор и поток запросов с недействительными учётными данными тоже ограничивались
+// и не нагружали проверку API-ключей в БД.
+func IPRateLimit(store ratelimit.Store, limit ratelimit.Limit, base *zap.Logger) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if limitRequest(c, store, limit, "ip|"+c.ClientIP(), base) {
+			c.Next()
+		}
+	}
+}
+
+// RateLimit ограничивает частоту запросов клиента: по API-ключу, пользователю из JWT,
+// а без аутентификации — по IP. Маршруты из routes (шаблоны вида "/total") ограничиваются
+// своими лимитами, остальные — общим лимитом def. Подключается после Authenticate.
+func RateLimit(store ratelimit.Store, def ratelimit.Limit, routes map[string]ratelimit.Limit, base *zap.Logger) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		limit, scope := def, defaultLimitScope
+		if l, ok := routes[c.FullPath()]; ok {
+			limit, scope = l, c.FullPath()
+		}
+		if limitRequest(c, store, limit, scope+"|"+rateLimitClient(c), base) {
+			c.Next()
+		}
+	}
+}
+
+// limitRequest забирает токен из ведра key и выставляет заголовки RateLimit-* (при нескольких
+// лимитах — последнего проверенного). При превышении лимита отвечает 429 с Retry-After и
+// возвращает false. Если store недоступно, запрос пропускается.
+func limitRequest(c *gin.Context, store ratelimit.Store, limit ratelimit.Limit, key string, base *zap.Logger) bool {
+	if limit.Unlimited() {
+		return true
+	}
+	ctx := c.Request.Context()
+	res, err := store.Take(ctx, key, limit)
+	if err != nil {
+		logger.FromContext(ctx, base).Warn("Rate limiter unavailable, request allowed", zap.Error(err))
+		return true
+	}
+	c.Header("RateLimit-Policy", fmt.Sprintf("%d;w=%d", limit.Requests, ceilSeconds(limit.Period)))
+	c.Header("RateLimit-Limit", strconv.Itoa(res.Limit))
+	c.Header("RateLimit-Remaining", strconv.Itoa(res.Remaining))
+	c.Header("RateLimit-Reset", strconv.Itoa(ceilSeconds(res.Reset)))
+	if !res.Allowed {
+		c.Header("Retry-After", strconv.Itoa(max(1, ceilSeconds(res.RetryAfter))))
+		metrics.RateLimited.WithLabelValues(c.FullPath()).Inc()
+		_ = c.Error(fmt.Errorf("%w: limit is %d requests per %s", errs.ErrRateLimited, limit.Requests, limit.Period))
+		c.Abort()
+		return false
+	}
+	return true
+}
+
+// rateLimitClient возвращает ключ клиента для ограничения частоты запросов.
+func rateLimitClient(c *gin.Context) string {
+	if p, ok := auth.FromContext(c.Request.Context()); ok {
+		if p.IsService() {
+			return "key:" + p.APIKeyID
+		}
+		return "user:" + p.UserID
+	}
+	return "ip:" + c.ClientIP()
+}
+
+func ceilSeconds(d time.Duration) int {
+	return int(math.Ceil(d.Seconds()))
+}
@@ -0,0 +1,93 @@
+package api
+
+import (
+	"github.com/Tommych123/subscription-service/internal/ratelimit"
+	"github.com/Tommych123/subscription-service/service/auth"
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestRateLimit(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	store := ratelimit.NewMemoryStore()
+	r := gin.New()
+	r.Use(ErrorHandler(zap.NewNop()))
+	// Вызывающий подставляется из заголовка X-Test-User, как это сделал бы Authenticate.
+	r.Use(func(c *gin.Context) {
+		if user := c.GetHeader("X-Test-User"); user != "" {
+			c.Request = c.Request.WithContext(auth.WithPrincipal(c.Request.Context(), &auth.Principal{UserID: user}))
+		}
+	})
+	r.Use(RateLimit(store, ratelimit.Limit{Requests: 2, Period: time.Minute}, map[string]ratelimit.Limit{"/total": {Requests: 1, Period: time.Minute}}, zap.NewNop()))
+	ok := func(c *gin.Context) { c.Status(http.StatusNoContent) }
+	r.GET("/subscriptions", ok)
+	r.GET("/total", ok)
+
+	get := func(path, user string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		if user != "" {
+			req.Header.Set("X-Test-User", user)
+		}
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	for i := range 2 {
+		if w := get("/subscriptions", alice); w.Code != http.StatusNoContent {
+			t.Fatalf("request %d: status = %d, want 204", i+1, w.Code)
+		}
+	}
+	w := get("/subscriptions", alice)
+	if w.Code != http.StatusTooManyRequests {
+		t.Fatalf("over the limit: status = %d, want 429", w.Code)
+	}
+	if w.Header().Get("Retry-After") == "" || w.Header().Get("RateLimit-Remaining") != "0" || w.Header().Get("RateLimit-Policy") != "2;w=60" {
+		t.Errorf("429 headers = %v, want Retry-After and RateLimit-*", w.Header())
+	}
+	if w.Header().Get("Content-Type") != problemContentType {
+		t.Errorf("Content-Type = %q, want %q", w.Header().Get("Content-Type"), problemContentType)
+	}
+
+	// У маршрута со своим лимитом отдельное ведро, у другого клиента — тоже.
+	if w := get("/total", alice); w.Code != http.StatusNoContent {
+		t.Errorf("/total after default limit is spent: status = %d, want 204", w.Code)
+	}
+	if w := get("/total", alice); w.Code != http.StatusTooManyRequests {
+		t.Errorf("/total over its limit: status = %d, want 429", w.Code)
+	}
+	if w := get("/subscriptions", "22222222-2222-2222-2222-222222222222"); w.Code != http.StatusNoContent {
+		t.Errorf("another user: status = %d, want 204", w.Code)
+	}
+	if w := get("/subscriptions", ""); w.Code != http.StatusNoContent {
+		t.Errorf("anonymous client: status = %d, want 204", w.Code)
+	}
+}
+
+func TestIPRateLimitBeforeAuthentication(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	verifier, err := auth.NewVerifier(testSecret, "", "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := gin.New()
+	r.Use(ErrorHandler(zap.NewNop()))
+	limited := r.Group("/", IPRateLimit(ratelimit.NewMemoryStore(), ratelimit.Limit{Requests: 2, Period: time.Minute}, zap.NewNop()), Authenticate(verifier, nil, auth.DefaultPolicy(), zap.NewNop()))
+	limited.GET("/subscriptions", func(c *gin.Context) { c.Status(http.StatusNoContent) })
+
+	// Запросы с недействительным токеном тоже расходуют лимит IP.
+	want := []int{http.StatusUnauthorized, http.StatusUnauthorized, http.StatusTooManyRequests}
+	for i, code := range want {
+		req := httptest.NewRequest(http.MethodGet, "/subscriptions", nil)
+		req.Header.Set("Authorization", "Bearer garbage")
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		if w.Code != code {
+			t.Fatalf("request %d: status = %d, want %d", i+1, w.Code, code)
+		}
+	}
+}
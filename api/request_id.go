@@ -0,0 +1,51 @@
+package api
+
+import (
+	"github.com/Tommych123/subscription-service/internal/logger"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"go.opentelemetry.io/otel/trace"
+	"go.uber.org/zap"
+)
+
+const requestIDHeader = "X-Request-ID"
+
+// maxRequestIDLength ограничивает длину X-Request-ID, принятого от клиента.
+const maxRequestIDLength = 128
+
+// RequestID принимает X-Request-ID клиента или генерирует новый, возвращает его в ответе
+// и кладёт в контекст запроса дочерний логгер с полями request_id, method и route
+// (и trace_id, если запрос трассируется). Подключается после Tracing.
+func RequestID(base *zap.Logger) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id := c.GetHeader(requestIDHeader)
+		if !validRequestID(id) {
+			id = uuid.New().String()
+		}
+		c.Header(requestIDHeader, id)
+		fields := []zap.Field{
+			zap.String("request_id", id),
+			zap.String("method", c.Request.Method),
+			zap.String("route", routeName(c)),
+		}
+		if sc := trace.SpanContextFromContext(c.Request.Context()); sc.IsValid() {
+			fields = append(fields, zap.String("trace_id", sc.TraceID().String()))
+		}
+		ctx := logger.WithContext(c.Request.Context(), base.With(fields...))
+		c.Request = c.Request.WithContext(ctx)
+		c.Next()
+	}
+}
+
+// validRequestID допускает непустые идентификаторы из печатных ASCII-символов разумной длины.
+func validRequestID(id string) bool {
+	if id == "" || len(id) > maxRequestIDLength {
+		return false
+	}
+	for i := 0; i < len(id); i++ {
+		if id[i] < 0x21 || id[i] > 0x7e {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,52 @@
+package api
+
+import (
+	"fmt"
+	"github.com/Tommych123/subscription-service/internal/logger"
+	"github.com/Tommych123/subscription-service/service/auth"
+	"github.com/Tommych123/subscription-service/service/errs"
+	"github.com/Tommych123/subscription-service/service/tenant"
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+const tenantHeader = "X-Tenant-ID"
+
+// Tenant определяет арендатора запроса и кладёт его в контекст, откуда его берёт репозиторий
+// для ограничения запросов. Арендатор берётся из API-ключа или claim tenant_id токена, а без них —
+// из заголовка X-Tenant-ID или defaultTenant. Заголовок, не совпадающий с арендатором ключа
+// или токена, даёт 403, некорректный идентификатор — 400. Подключается после Authenticate.
+func Tenant(defaultTenant string, base *zap.Logger) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx := c.Request.Context()
+		header := c.GetHeader(tenantHeader)
+		if header != "" && !tenant.Valid(header) {
+			_ = c.Error(fmt.Errorf("%w: invalid %s header", errs.ErrInvalidInput, tenantHeader))
+			c.Abort()
+			return
+		}
+		tenantID := header
+		if p, ok := auth.FromContext(ctx); ok {
+			tenantID = p.TenantID
+			if tenantID == "" {
+				tenantID = defaultTenant
+			}
+			if !tenant.Valid(tenantID) {
+				_ = c.Error(fmt.Errorf("%w: invalid tenant_id claim", errs.ErrUnauthorized))
+				c.Abort()
+				return
+			}
+			if header != "" && header != tenantID {
+				_ = c.Error(fmt.Errorf("%w: credentials are not valid for tenant %s", errs.ErrForbidden, header))
+				c.Abort()
+				return
+			}
+		} else if tenantID == "" {
+			tenantID = defaultTenant
+		}
+		ctx = tenant.WithTenant(ctx, tenantID)
+		ctx = logger.WithContext(ctx, logger.FromContext(ctx, base).With(zap.String("tenant_id", tenantID)))
+		c.Request = c.Request.WithContext(ctx)
+		c.Next()
+	}
+}
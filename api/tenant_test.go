@@ -0,0 +1,69 @@
+package api
+
+import (
+	"github.com/Tommych123/subscription-service/service/auth"
+	"github.com/Tommych123/subscription-service/service/tenant"
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestTenant(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	// principal подставляет вызывающего из заголовка X-Test-Tenant, как это сделал бы Authenticate.
+	principal := func(c *gin.Context) {
+		if claim, ok := c.Request.Header["X-Test-Tenant"]; ok {
+			ctx := auth.WithPrincipal(c.Request.Context(), &auth.Principal{UserID: alice, TenantID: claim[0]})
+			c.Request = c.Request.WithContext(ctx)
+		}
+	}
+	r := gin.New()
+	r.Use(ErrorHandler(zap.NewNop()), principal, Tenant("default", zap.NewNop()))
+	r.GET("/tenant", func(c *gin.Context) {
+		id, _ := tenant.FromContext(c.Request.Context())
+		c.String(http.StatusOK, id)
+	})
+
+	tests := []struct {
+		name       string
+		header     string
+		claim      *string
+		want       int
+		wantTenant string
+	}{
+		{name: "anonymous default", want: http.StatusOK, wantTenant: "default"},
+		{name: "anonymous header", header: "acme", want: http.StatusOK, wantTenant: "acme"},
+		{name: "invalid header", header: "acme corp", want: http.StatusBadRequest},
+		{name: "claim", claim: ptr("acme"), want: http.StatusOK, wantTenant: "acme"},
+		{name: "no claim", claim: ptr(""), want: http.StatusOK, wantTenant: "default"},
+		{name: "header matches claim", header: "acme", claim: ptr("acme"), want: http.StatusOK, wantTenant: "acme"},
+		{name: "header differs from claim", header: "globex", claim: ptr("acme"), want: http.StatusForbidden},
+		{name: "header differs from default tenant", header: "globex", claim: ptr(""), want: http.StatusForbidden},
+		{name: "invalid claim", claim: ptr("acme/../globex"), want: http.StatusUnauthorized},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/tenant", nil)
+			if tt.header != "" {
+				req.Header.Set(tenantHeader, tt.header)
+			}
+			if tt.claim != nil {
+				req.Header.Set("X-Test-Tenant", *tt.claim)
+			}
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+			if w.Code != tt.want {
+				t.Fatalf("status = %d, want %d: %s", w.Code, tt.want, w.Body)
+			}
+			if tt.want == http.StatusOK && w.Body.String() != tt.wantTenant {
+				t.Errorf("tenant = %q, want %q", w.Body.String(), tt.wantTenant)
+			}
+		})
+	}
+}
+
+func ptr(s string) *string {
+	return &s
+}
@@ -0,0 +1,18 @@
+package api
+
+import (
+	"context"
+	"github.com/gin-gonic/gin"
+	"time"
+)
+
+// Timeout ограничивает контекст запроса временем d. Контекст передаётся в сервис и репозиторий,
+// поэтому запросы к БД, не уложившиеся в срок, отменяются, а клиент получает 504.
+func Timeout(d time.Duration) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx, cancel := context.WithTimeout(c.Request.Context(), d)
+		defer cancel()
+		c.Request = c.Request.WithContext(ctx)
+		c.Next()
+	}
+}
@@ -3,49 +3,201 @@
 // @description REST API для управления подписками пользователей
 // @host localhost:8080
 // @BasePath /
+// @securityDefinitions.apikey BearerAuth
+// @in header
+// @name Authorization
+// @description JWT в формате "Bearer <token>"
+// @securityDefinitions.apikey ApiKeyAuth
+// @in header
+// @name X-API-Key
+// @description API-ключ сервисного клиента
 package main
 
 import (
+	"context"
+	"errors"
 	"github.com/Tommych123/subscription-service/api"
 	_ "github.com/Tommych123/subscription-service/internal/docs"
 	"github.com/Tommych123/subscription-service/internal/logger"
+	"github.com/Tommych123/subscription-service/internal/metrics"
+	"github.com/Tommych123/subscription-service/internal/ratelimit"
+	"github.com/Tommych123/subscription-service/internal/tracing"
 	"github.com/Tommych123/subscription-service/pkg/db"
 	"github.com/Tommych123/subscription-service/repository"
 	"github.com/Tommych123/subscription-service/service"
+	"github.com/Tommych123/subscription-service/service/auth"
 	"github.com/Tommych123/subscription-service/service/config"
+	"github.com/Tommych123/subscription-service/service/tenant"
 	"github.com/gin-gonic/gin"
+	"github.com/jmoiron/sqlx"
 	"github.com/joho/godotenv"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
 	swaggerFiles "github.com/swaggo/files"
 	ginSwagger "github.com/swaggo/gin-swagger"
 	"go.uber.org/zap"
+	"net/http"
 	"os"
+	"os/signal"
+	"syscall"
 )
 
 func main() {
 	if err := godotenv.Load(); err != nil {
 		println("No .env file found, reading from environment")
 	}
-	logg, err := logger.NewLogger()
+	bootLog, _, err := logger.NewLogger(logger.Options{})
 	if err != nil {
 		panic("failed to initialize logger: " + err.Error())
 	}
+	bootLog.Info("Starting application")
+	cfg := config.LoadConfig(bootLog)
+	logg, logLevel, err := logger.NewLogger(logger.Options{Level: cfg.LogLevel, Format: cfg.LogFormat, Output: cfg.LogOutput})
+	if err != nil {
+		bootLog.Fatal("Failed to initialize logger", zap.Error(err))
+	}
 	defer logg.Sync()
-	logg.Info("Starting application")
-	cfg := config.LoadConfig(logg)
-	sqlxDB := db.NewPostgres(cfg, logg)
-	db.RunMigrations(sqlxDB, cfg, logg)
-	repo := repository.NewSubscriptionRepository(sqlxDB)
-	svc := service.NewSubscriptionService(repo, logg)
-	h := api.NewSubscriptionHandler(svc, logg)
-	r := gin.Default()
-	h.RegisterRoutes(r)
+	if len(os.Args) > 1 && os.Args[1] == "mint-api-key" {
+		if err := mintAPIKey(cfg, logg, os.Args[2:]); err != nil {
+			logg.Fatal("Failed to mint API key", zap.Error(err))
+		}
+		return
+	}
+	shutdownTracing, err := tracing.Setup(context.Background(), cfg, logg)
+	if err != nil {
+		logg.Fatal("Failed to initialize tracing", zap.Error(err))
+	}
+	var repo service.SubscriptionStore
+	var rates service.ExchangeRateProvider
+	var apiKeys service.APIKeyStore
+	var sqlxDB *sqlx.DB
+	var checks []api.HealthCheck
+	switch cfg.Storage {
+	case "postgres":
+		sqlxDB = db.NewPostgres(cfg, logg)
+		db.RunMigrations(sqlxDB, cfg, logg)
+		db.CheckTenantRLS(sqlxDB, cfg.TenantRLS, logg)
+		repo = repository.NewSubscriptionRepository(sqlxDB, cfg.TenantRLS)
+		rates = repository.NewExchangeRateRepository(sqlxDB)
+		apiKeys = repository.NewAPIKeyRepository(sqlxDB)
+		expectedVersion, err := db.LatestMigrationVersion()
+		if err != nil {
+			logg.Fatal("Failed to read migrations", zap.Error(err))
+		}
+		metrics.RegisterDBStats(sqlxDB.DB, cfg.DBName)
+		checks = append(checks,
+			api.HealthCheck{Name: "postgres", Check: sqlxDB.PingContext},
+			api.HealthCheck{Name: "migrations", Check: func(ctx context.Context) error {
+				return db.CheckMigrations(ctx, sqlxDB, expectedVersion)
+			}},
+		)
+	case "memory":
+		logg.Warn("Using in-memory storage, data will be lost on restart")
+		repo = repository.NewMemorySubscriptionRepository()
+		rates = repository.NewMemoryExchangeRateRepository()
+		apiKeys = repository.NewMemoryAPIKeyRepository()
+	default:
+		logg.Fatal("Unknown storage", zap.String("storage", cfg.Storage))
+	}
+	if !tenant.Valid(cfg.DefaultTenant) {
+		logg.Fatal("Invalid default tenant", zap.String("tenant", cfg.DefaultTenant))
+	}
+	svc := service.NewSubscriptionService(repo, rates, logg)
+	keysSvc := service.NewAPIKeyService(apiKeys, logg)
+	metrics.RegisterActiveSubscriptions(svc.CountActive, logg)
+	h := api.NewSubscriptionHandler(svc)
+	r := gin.New()
+	if err := r.SetTrustedProxies(cfg.TrustedProxies); err != nil {
+		logg.Fatal("Invalid trusted proxies", zap.Error(err))
+	}
+	r.Use(
+		api.Recovery(logg),
+		api.Metrics(),
+		api.Tracing(),
+		api.RequestID(logg),
+		api.AccessLog(logg, cfg.AccessLogSampleRate, cfg.AccessLogSkipPaths),
+		api.ErrorHandler(logg),
+		api.Timeout(cfg.QueryTimeout),
+	)
+	r.NoRoute(api.NoRoute)
+	limits := ratelimit.NewMemoryStore()
+	ipLimiter := api.IPRateLimit(limits, cfg.RateLimitIP, logg)
+	protected := r.Group("/", ipLimiter)
+	// admin остаётся nil, если право admin некому получить: без аутентификации или без JWT.
+	var admin *gin.RouterGroup
+	if cfg.AuthDisabled {
+		logg.Warn("AUTH_DISABLED is set: API is open to everyone and /admin is not served, use only for local development")
+	} else {
+		var verifier *auth.Verifier
+		if cfg.JWTSecret != "" || cfg.JWKSFile != "" {
+			if verifier, err = auth.NewVerifier(cfg.JWTSecret, cfg.JWKSFile, cfg.JWTIssuer, cfg.JWTAudience); err != nil {
+				logg.Fatal("Failed to initialize JWT verifier", zap.Error(err))
+			}
+		} else {
+			logg.Warn("JWT_SECRET and JWT_JWKS_FILE are not set: only API keys are accepted and /admin is not served")
+		}
+		policy := auth.DefaultPolicy()
+		if cfg.PolicyFile != "" {
+			if policy, err = auth.LoadPolicy(cfg.PolicyFile); err != nil {
+				logg.Fatal("Failed to load RBAC policy", zap.Error(err))
+			}
+		}
+		protected.Use(api.Authenticate(verifier, keysSvc, policy, logg))
+		if verifier != nil {
+			admin = r.Group("/", ipLimiter, api.Authenticate(verifier, keysSvc, policy, logg))
+		}
+	}
+	limiter := api.RateLimit(limits, cfg.RateLimitDefault, cfg.RateLimitRoutes, logg)
+	protected.Use(api.Tenant(cfg.DefaultTenant, logg), limiter)
+	h.RegisterRoutes(protected)
+	if admin != nil {
+		admin.Use(api.Tenant(cfg.DefaultTenant, logg), limiter)
+		api.NewAdminHandler(logLevel, logg).RegisterRoutes(admin)
+		api.NewAPIKeyHandler(keysSvc).RegisterRoutes(admin)
+	}
+	api.NewHealthHandler(checks...).RegisterRoutes(r)
+	r.GET("/metrics", gin.WrapH(promhttp.Handler()))
 	r.GET("/swagger/*any", ginSwagger.WrapHandler(swaggerFiles.Handler))
 	port := os.Getenv("SERVER_PORT")
 	if port == "" {
 		port = cfg.ServerPort
 	}
-	logg.Info("Server running", zap.String("port", port))
-	if err := r.Run(":" + port); err != nil {
-		logg.Fatal("Server failed", zap.Error(err))
+	srv := &http.Server{
+		Addr:              ":" + port,
+		Handler:           r,
+		ReadTimeout:       cfg.ReadTimeout,
+		ReadHeaderTimeout: cfg.ReadHeaderTimeout,
+		WriteTimeout:      cfg.WriteTimeout,
+		IdleTimeout:       cfg.IdleTimeout,
+	}
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+	serverErr := make(chan error, 1)
+	go func() {
+		logg.Info("Server running", zap.String("port", port))
+		serverErr <- srv.ListenAndServe()
+	}()
+	select {
+	case err := <-serverErr:
+		if !errors.Is(err, http.ErrServerClosed) {
+			logg.Fatal("Server failed", zap.Error(err))
+		}
+	case <-ctx.Done():
+		stop()
+		logg.Info("Shutdown signal received, draining in-flight requests", zap.Duration("grace_period", cfg.ShutdownTimeout))
+	}
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), cfg.ShutdownTimeout)
+	defer cancel()
+	if err := srv.Shutdown(shutdownCtx); err != nil {
+		logg.Error("Graceful shutdown did not complete, closing remaining connections", zap.Error(err))
+		_ = srv.Close()
+	}
+	if err := shutdownTracing(shutdownCtx); err != nil {
+		logg.Error("Failed to flush traces", zap.Error(err))
+	}
+	if sqlxDB != nil {
+		if err := sqlxDB.Close(); err != nil {
+			logg.Error("Failed to close DB connection pool", zap.Error(err))
+		}
 	}
+	logg.Info("Server stopped")
 }
@@ -0,0 +1,62 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"github.com/Tommych123/subscription-service/models"
+	"github.com/Tommych123/subscription-service/pkg/db"
+	"github.com/Tommych123/subscription-service/repository"
+	"github.com/Tommych123/subscription-service/service"
+	"github.com/Tommych123/subscription-service/service/config"
+	"github.com/Tommych123/subscription-service/service/tenant"
+	"go.uber.org/zap"
+	"os"
+	"strings"
+	"time"
+)
+
+// mintAPIKey выпускает API-ключ напрямую в БД и печатает его в stdout. Нужна для развёртываний
+// без JWT, где эндпоинты /admin не публикуются, и для выпуска первого ключа.
+func mintAPIKey(cfg *config.Config, log *zap.Logger, args []string) error {
+	fs := flag.NewFlagSet("mint-api-key", flag.ContinueOnError)
+	name := fs.String("name", "", "имя ключа, например billing-cron")
+	scopes := fs.String("scopes", "", "области доступа через запятую, например subscriptions:read,totals:read")
+	tenantID := fs.String("tenant", cfg.DefaultTenant, "арендатор, в котором действует ключ")
+	expires := fs.String("expires", "", "срок действия в формате RFC 3339, например 2026-12-31T00:00:00Z")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if cfg.Storage != "postgres" {
+		return errors.New("mint-api-key requires STORAGE=postgres")
+	}
+	if !tenant.Valid(*tenantID) {
+		return fmt.Errorf("invalid tenant %q", *tenantID)
+	}
+	req := models.NewAPIKey{Name: *name}
+	for _, scope := range strings.Split(*scopes, ",") {
+		if scope = strings.TrimSpace(scope); scope != "" {
+			req.Scopes = append(req.Scopes, scope)
+		}
+	}
+	if *expires != "" {
+		t, err := time.Parse(time.RFC3339, *expires)
+		if err != nil {
+			return fmt.Errorf("invalid -expires: %w", err)
+		}
+		req.ExpiresAt = &t
+	}
+	sqlxDB := db.NewPostgres(cfg, log)
+	defer sqlxDB.Close()
+	db.RunMigrations(sqlxDB, cfg, log)
+	keys := service.NewAPIKeyService(repository.NewAPIKeyRepository(sqlxDB), log)
+	issued, err := keys.Mint(tenant.WithTenant(context.Background(), *tenantID), req)
+	if err != nil {
+		return err
+	}
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "  ")
+	return enc.Encode(issued)
+}
@@ -1,13 +1,58 @@
 package logger
 
 import (
+	"context"
+	"fmt"
 	"go.uber.org/zap"
 	"go.uber.org/zap/zapcore"
 )
 
-func NewLogger() (*zap.Logger, error) {
+// Options — настройки логгера: уровень (debug, info, warn, error), формат (json или console)
+// и вывод (stdout, stderr или путь к файлу). Пустые значения заменяются на info, json и stderr.
+type Options struct {
+	Level  string
+	Format string
+	Output string
+}
+
+// NewLogger создаёт логгер и возвращает его уровень, который можно менять во время работы.
+func NewLogger(opts Options) (*zap.Logger, zap.AtomicLevel, error) {
 	cfg := zap.NewProductionConfig()
 	cfg.EncoderConfig.TimeKey = "time"
 	cfg.EncoderConfig.EncodeTime = zapcore.ISO8601TimeEncoder
-	return cfg.Build()
+	if opts.Level != "" {
+		level, err := zap.ParseAtomicLevel(opts.Level)
+		if err != nil {
+			return nil, cfg.Level, err
+		}
+		cfg.Level = level
+	}
+	switch opts.Format {
+	case "", "json":
+	case "console":
+		cfg.Encoding = "console"
+		cfg.EncoderConfig.EncodeLevel = zapcore.CapitalLevelEncoder
+	default:
+		return nil, cfg.Level, fmt.Errorf("unknown log format %q", opts.Format)
+	}
+	if opts.Output != "" {
+		cfg.OutputPaths = []string{opts.Output}
+	}
+	l, err := cfg.Build()
+	return l, cfg.Level, err
+}
+
+type ctxKey struct{}
+
+// WithContext возвращает копию ctx, в которой хранится логгер l.
+func WithContext(ctx context.Context, l *zap.Logger) context.Context {
+	return context.WithValue(ctx, ctxKey{}, l)
+}
+
+// FromContext возвращает логгер запроса из ctx, а если его нет — fallback.
+func FromContext(ctx context.Context, fallback *zap.Logger) *zap.Logger {
+	if l, ok := ctx.Value(ctxKey{}).(*zap.Logger); ok {
+		return l
+	}
+	return fallback
 }
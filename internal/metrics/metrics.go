@@ -0,0 +1,108 @@
+package metrics
+
+import (
+	"context"
+	"database/sql"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"go.uber.org/zap"
+	"sync"
+	"time"
+)
+
+const namespace = "subscription_service"
+
+// scrapeTimeout ограничивает время запроса к хранилищу при сборе бизнес-метрик.
+const scrapeTimeout = 5 * time.Second
+
+// activeCacheTTL — как долго число активных подписок берётся из кэша, а не из базы:
+// частые сборы метрик не должны каждый раз сканировать таблицу подписок.
+const activeCacheTTL = 30 * time.Second
+
+var (
+	HTTPRequests = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "http_requests_total",
+		Help:      "Количество HTTP-запросов по маршруту, методу и статусу.",
+	}, []string{"method", "route", "status"})
+
+	HTTPRequestDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "http_request_duration_seconds",
+		Help:      "Длительность обработки HTTP-запросов.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"method", "route", "status"})
+
+	RateLimited = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "rate_limited_requests_total",
+		Help:      "Количество запросов, отклонённых ограничением частоты, по маршруту.",
+	}, []string{"route"})
+
+	TotalCostDuration = promauto.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "total_cost_last_duration_seconds",
+		Help:      "Длительность последнего расчёта суммарной стоимости подписок.",
+	})
+)
+
+// RegisterDBStats публикует статистику пула соединений sql.DBStats.
+func RegisterDBStats(db *sql.DB, dbName string) {
+	prometheus.MustRegister(collectors.NewDBStatsCollector(db, dbName))
+}
+
+// RegisterActiveSubscriptions публикует число активных подписок; count вызывается не чаще раза в activeCacheTTL.
+func RegisterActiveSubscriptions(count func(ctx context.Context) (int, error), logger *zap.Logger) {
+	prometheus.MustRegister(&activeSubscriptionsCollector{
+		count:  count,
+		logger: logger,
+		desc: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "", "active_subscriptions"),
+			"Количество подписок, активных в текущем месяце.",
+			nil, nil,
+		),
+	})
+}
+
+type activeSubscriptionsCollector struct {
+	count  func(ctx context.Context) (int, error)
+	logger *zap.Logger
+	desc   *prometheus.Desc
+
+	mu        sync.Mutex
+	cached    int
+	updatedAt time.Time
+}
+
+func (c *activeSubscriptionsCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.desc
+}
+
+func (c *activeSubscriptionsCollector) Collect(ch chan<- prometheus.Metric) {
+	n, err := c.active()
+	if err != nil {
+		c.logger.Warn("Failed to count active subscriptions", zap.Error(err))
+		ch <- prometheus.NewInvalidMetric(c.desc, err)
+		return
+	}
+	ch <- prometheus.MustNewConstMetric(c.desc, prometheus.GaugeValue, float64(n))
+}
+
+// active возвращает закэшированное значение, пока оно не старше activeCacheTTL. Блокировка
+// держится на время запроса, чтобы параллельные сборы не считали одно и то же одновременно.
+func (c *activeSubscriptionsCollector) active() (int, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if !c.updatedAt.IsZero() && time.Since(c.updatedAt) < activeCacheTTL {
+		return c.cached, nil
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), scrapeTimeout)
+	defer cancel()
+	n, err := c.count(ctx)
+	if err != nil {
+		return 0, err
+	}
+	c.cached, c.updatedAt = n, time.Now()
+	return n, nil
+}
@@ -0,0 +1,36 @@
+package metrics
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestActiveSubscriptionsCache(t *testing.T) {
+	calls := 0
+	var countErr error
+	c := &activeSubscriptionsCollector{count: func(ctx context.Context) (int, error) {
+		calls++
+		return calls, countErr
+	}}
+
+	for range 3 {
+		if n, err := c.active(); err != nil || n != 1 {
+			t.Fatalf("active() = %d, %v, want cached 1", n, err)
+		}
+	}
+	if calls != 1 {
+		t.Errorf("count called %d times within TTL, want 1", calls)
+	}
+
+	c.updatedAt = time.Now().Add(-activeCacheTTL)
+	countErr = errors.New("db is down")
+	if _, err := c.active(); !errors.Is(err, countErr) {
+		t.Errorf("active() after TTL error = %v, want count error", err)
+	}
+	countErr = nil
+	if n, err := c.active(); err != nil || n != 3 {
+		t.Errorf("active() after failed refresh = %d, %v, want fresh 3", n, err)
+	}
+}
@@ -0,0 +1,145 @@
+package ratelimit
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestParseLimit(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    Limit
+		wantErr bool
+	}{
+		{in: "30/1m", want: Limit{Requests: 30, Period: time.Minute}},
+		{in: "30/m", want: Limit{Requests: 30, Period: time.Minute}},
+		{in: "5/s", want: Limit{Requests: 5, Period: time.Second}},
+		{in: " 100/10s ", want: Limit{Requests: 100, Period: 10 * time.Second}},
+		{in: "1000/h", want: Limit{Requests: 1000, Period: time.Hour}},
+		{in: "", want: Limit{}},
+		{in: "0", want: Limit{}},
+		{in: "30", wantErr: true},
+		{in: "0/1m", wantErr: true},
+		{in: "-1/1m", wantErr: true},
+		{in: "x/1m", wantErr: true},
+		{in: "30/", wantErr: true},
+		{in: "30/fortnight", wantErr: true},
+		{in: "30/-1m", wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := ParseLimit(tt.in)
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("ParseLimit(%q) = %v, want error", tt.in, got)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("ParseLimit(%q) error: %v", tt.in, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("ParseLimit(%q) = %+v, want %+v", tt.in, got, tt.want)
+		}
+	}
+}
+
+// newTestStore возвращает MemoryStore с управляемыми часами.
+func newTestStore() (*MemoryStore, *time.Time) {
+	now := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	s := NewMemoryStore()
+	s.now = func() time.Time { return now }
+	return s, &now
+}
+
+func take(t *testing.T, s *MemoryStore, key string, limit Limit) Result {
+	t.Helper()
+	res, err := s.Take(context.Background(), key, limit)
+	if err != nil {
+		t.Fatalf("Take(%q) error: %v", key, err)
+	}
+	return res
+}
+
+func TestMemoryStoreBurstAndRetryAfter(t *testing.T) {
+	s, _ := newTestStore()
+	limit := Limit{Requests: 3, Period: 30 * time.Second}
+	for i := 2; i >= 0; i-- {
+		res := take(t, s, "k", limit)
+		if !res.Allowed || res.Remaining != i || res.Limit != 3 {
+			t.Fatalf("request %d: got %+v, want allowed with %d remaining", 3-i, res, i)
+		}
+	}
+	res := take(t, s, "k", limit)
+	if res.Allowed {
+		t.Fatalf("4th request allowed: %+v", res)
+	}
+	if res.RetryAfter != 10*time.Second {
+		t.Errorf("RetryAfter = %s, want 10s", res.RetryAfter)
+	}
+	if res.Reset != 30*time.Second {
+		t.Errorf("Reset = %s, want 30s", res.Reset)
+	}
+	if other := take(t, s, "other", limit); !other.Allowed {
+		t.Errorf("other key shares the bucket: %+v", other)
+	}
+}
+
+func TestMemoryStoreRefill(t *testing.T) {
+	s, now := newTestStore()
+	limit := Limit{Requests: 2, Period: 10 * time.Second}
+	take(t, s, "k", limit)
+	take(t, s, "k", limit)
+	if res := take(t, s, "k", limit); res.Allowed {
+		t.Fatalf("empty bucket allowed request: %+v", res)
+	}
+
+	*now = now.Add(4 * time.Second)
+	if res := take(t, s, "k", limit); res.Allowed {
+		t.Fatalf("request allowed before a token refilled: %+v", res)
+	}
+	*now = now.Add(time.Second)
+	res := take(t, s, "k", limit)
+	if !res.Allowed || res.Remaining != 0 {
+		t.Fatalf("after 5s: got %+v, want allowed with 0 remaining", res)
+	}
+
+	*now = now.Add(time.Hour)
+	res = take(t, s, "k", limit)
+	if !res.Allowed || res.Remaining != 1 {
+		t.Errorf("after idle: got %+v, want allowed with 1 remaining (refill capped at Requests)", res)
+	}
+}
+
+func TestMemoryStoreUnlimited(t *testing.T) {
+	s, _ := newTestStore()
+	for i := 0; i < 100; i++ {
+		if res := take(t, s, "k", Limit{}); !res.Allowed {
+			t.Fatalf("unlimited request %d rejected", i)
+		}
+	}
+	if len(s.buckets) != 0 {
+		t.Errorf("unlimited requests created %d buckets", len(s.buckets))
+	}
+}
+
+func TestMemoryStoreSweep(t *testing.T) {
+	s, now := newTestStore()
+	short := Limit{Requests: 1, Period: time.Second}
+	long := Limit{Requests: 1, Period: time.Hour}
+	take(t, s, "idle", short)
+	take(t, s, "busy", long)
+
+	*now = now.Add(sweepInterval)
+	take(t, s, "trigger", short)
+	if _, ok := s.buckets["idle"]; ok {
+		t.Error("full bucket of an idle client was not swept")
+	}
+	if _, ok := s.buckets["busy"]; !ok {
+		t.Error("bucket that is still refilling was swept")
+	}
+	if res := take(t, s, "busy", long); res.Allowed {
+		t.Errorf("sweep reset a refilling bucket: %+v", res)
+	}
+}
@@ -0,0 +1 @@
+ALTER TABLE subscriptions ALTER COLUMN price TYPE INTEGER USING round(price)::integer;
\ No newline at end of file
@@ -0,0 +1 @@
+ALTER TABLE subscriptions ALTER COLUMN price TYPE NUMERIC(14, 2);
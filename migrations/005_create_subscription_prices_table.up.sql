@@ -0,0 +1,6 @@
+CREATE TABLE IF NOT EXISTS subscription_prices (
+    subscription_id UUID NOT NULL REFERENCES subscriptions (id) ON DELETE CASCADE,
+    effective_from DATE NOT NULL,
+    price NUMERIC(14, 2) NOT NULL CHECK (price >= 0),
+    PRIMARY KEY (subscription_id, effective_from)
+);
@@ -0,0 +1,4 @@
+ALTER TABLE api_keys DROP COLUMN IF EXISTS tenant_id;
+
+DROP INDEX IF EXISTS subscriptions_tenant_user_idx;
+ALTER TABLE subscriptions DROP COLUMN IF EXISTS tenant_id;
@@ -0,0 +1,7 @@
+ALTER TABLE subscription_prices NO FORCE ROW LEVEL SECURITY;
+ALTER TABLE subscription_prices DISABLE ROW LEVEL SECURITY;
+DROP POLICY IF EXISTS subscription_prices_tenant_isolation ON subscription_prices;
+
+ALTER TABLE subscriptions NO FORCE ROW LEVEL SECURITY;
+ALTER TABLE subscriptions DISABLE ROW LEVEL SECURITY;
+DROP POLICY IF EXISTS subscriptions_tenant_isolation ON subscriptions;
@@ -0,0 +1,13 @@
+-- Политики пропускают только строки арендатора из переменной сеанса app.tenant_id, а внутренним
+-- задачам сервиса, работающим со всеми арендаторами, — все строки при app.all_tenants = 'on'.
+-- Без этих переменных не видно ни одной строки. Политики действуют, только пока для таблиц
+-- включена row-level security: её включают вместе с TENANT_RLS=true (SQL — в README).
+CREATE POLICY subscriptions_tenant_isolation ON subscriptions
+    USING (tenant_id = current_setting('app.tenant_id', true)
+        OR current_setting('app.all_tenants', true) = 'on')
+    WITH CHECK (tenant_id = current_setting('app.tenant_id', true)
+        OR current_setting('app.all_tenants', true) = 'on');
+
+CREATE POLICY subscription_prices_tenant_isolation ON subscription_prices
+    USING (EXISTS (SELECT 1 FROM subscriptions s WHERE s.id = subscription_id))
+    WITH CHECK (EXISTS (SELECT 1 FROM subscriptions s WHERE s.id = subscription_id));
@@ -0,0 +1,52 @@
+package models
+
+import (
+	"time"
+)
+
+// Области доступа API-ключей.
+const (
+	ScopeSubscriptionsRead  = "subscriptions:read"
+	ScopeSubscriptionsWrite = "subscriptions:write"
+	ScopeTotalsRead         = "totals:read"
+)
+
+// APIKeyScopes — все допустимые области доступа.
+var APIKeyScopes = map[string]bool{
+	ScopeSubscriptionsRead:  true,
+	ScopeSubscriptionsWrite: true,
+	ScopeTotalsRead:         true,
+}
+
+// APIKey — ключ доступа для сервисных клиентов. Сам ключ не хранится, только его хеш;
+// Prefix — начало ключа, по которому его можно узнать в списке. Ключ действует только
+// в пределах арендатора TenantID.
+type APIKey struct {
+	ID         string     `json:"id" example:"2f6b1c1e-8d7a-4c1e-9f5e-1d2c3b4a5e6f"`
+	TenantID   string     `json:"tenant_id" example:"default"`
+	Name       string     `json:"name" example:"billing-cron"`
+	Prefix     string     `json:"prefix" example:"sk_3Hq9xA"`
+	Scopes     []string   `json:"scopes" example:"subscriptions:read,totals:read"`
+	CreatedAt  time.Time  `json:"created_at"`
+	ExpiresAt  *time.Time `json:"expires_at,omitempty"`
+	LastUsedAt *time.Time `json:"last_used_at,omitempty"`
+	RevokedAt  *time.Time `json:"revoked_at,omitempty"`
+}
+
+// NewAPIKey — запрос на выпуск API-ключа.
+type NewAPIKey struct {
+	Name      string     `json:"name" example:"billing-cron"`
+	Scopes    []string   `json:"scopes" example:"subscriptions:read,totals:read"`
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+}
+
+// IssuedAPIKey — выпущенный ключ; Key возвращается только один раз.
+type IssuedAPIKey struct {
+	APIKey
+	Key string `json:"key" example:"sk_3Hq9xA..."`
+}
+
+// Active сообщает, можно ли использовать ключ в момент now.
+func (k *APIKey) Active(now time.Time) bool {
+	return k.RevokedAt == nil && (k.ExpiresAt == nil || now.Before(*k.ExpiresAt))
+}
@@ -0,0 +1,67 @@
+package models
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// DefaultCurrency — валюта подписок, для которых она не указана, и итогов по умолчанию.
+const DefaultCurrency Currency = "RUB"
+
+// Currency — трёхбуквенный код валюты ISO 4217.
+type Currency string
+
+// iso4217 — действующие коды валют ISO 4217.
+var iso4217 = map[Currency]bool{}
+
+func init() {
+	codes := "AED AFN ALL AMD ANG AOA ARS AUD AWG AZN BAM BBD BDT BGN BHD BIF BMD BND BOB BRL BSD BTN BWP BYN BZD " +
+		"CAD CDF CHF CLP CNY COP CRC CUP CVE CZK DJF DKK DOP DZD EGP ERN ETB EUR FJD FKP GBP GEL GHS GIP GMD GNF " +
+		"GTQ GYD HKD HNL HTG HUF IDR ILS INR IQD IRR ISK JMD JOD JPY KES KGS KHR KMF KPW KRW KWD KYD KZT LAK LBP " +
+		"LKR LRD LSL LYD MAD MDL MGA MKD MMK MNT MOP MRU MUR MVR MWK MXN MYR MZN NAD NGN NIO NOK NPR NZD OMR PAB " +
+		"PEN PGK PHP PKR PLN PYG QAR RON RSD RUB RWF SAR SBD SCR SDG SEK SGD SHP SLE SOS SRD SSP STN SVC SYP SZL " +
+		"THB TJS TMT TND TOP TRY TTD TWD TZS UAH UGX USD UYU UZS VES VND VUV WST XAF XCD XOF XPF YER ZAR ZMW ZWG"
+	for _, code := range strings.Fields(codes) {
+		iso4217[Currency(code)] = true
+	}
+}
+
+func (c Currency) Valid() bool {
+	return iso4217[c]
+}
+
+// ParseCurrency приводит код к верхнему регистру и проверяет его по ISO 4217.
+func ParseCurrency(s string) (Currency, error) {
+	c := Currency(strings.ToUpper(strings.TrimSpace(s)))
+	if !c.Valid() {
+		return "", fmt.Errorf("invalid currency %q, expected ISO 4217 code", s)
+	}
+	return c, nil
+}
+
+func (c *Currency) UnmarshalJSON(data []byte) error {
+	var str string
+	if err := json.Unmarshal(data, &str); err != nil {
+		return fmt.Errorf("invalid currency: %v", err)
+	}
+	if str == "" {
+		*c = DefaultCurrency
+		return nil
+	}
+	parsed, err := ParseCurrency(str)
+	if err != nil {
+		return err
+	}
+	*c = parsed
+	return nil
+}
+
+// Value подставляет валюту по умолчанию, если она не указана.
+func (c Currency) Value() (driver.Value, error) {
+	if c == "" {
+		return string(DefaultCurrency), nil
+	}
+	return string(c), nil
+}
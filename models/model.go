@@ -2,6 +2,7 @@ package models
 
 import (
 	"database/sql/driver"
+	"encoding/json"
 	"fmt"
 	"time"
 )
@@ -73,11 +74,208 @@ func (m MonthYear) Value() (driver.Value, error) {
 	return m.Time.Format("2006-01-02"), nil
 }
 
+// BillingPeriod — периодичность списания стоимости подписки.
+type BillingPeriod string
+
+const (
+	BillingWeekly    BillingPeriod = "weekly"
+	BillingMonthly   BillingPeriod = "monthly"
+	BillingQuarterly BillingPeriod = "quarterly"
+	BillingAnnual    BillingPeriod = "annual"
+)
+
+func (p BillingPeriod) Valid() bool {
+	switch p {
+	case BillingWeekly, BillingMonthly, BillingQuarterly, BillingAnnual:
+		return true
+	}
+	return false
+}
+
+func (p *BillingPeriod) UnmarshalJSON(data []byte) error {
+	var str string
+	if err := json.Unmarshal(data, &str); err != nil {
+		return fmt.Errorf("invalid billing period: %v", err)
+	}
+	if str == "" {
+		*p = BillingMonthly
+		return nil
+	}
+	if !BillingPeriod(str).Valid() {
+		return fmt.Errorf("invalid billing period %q, expected weekly, monthly, quarterly or annual", str)
+	}
+	*p = BillingPeriod(str)
+	return nil
+}
+
+// Value подставляет monthly, если период не указан.
+func (p BillingPeriod) Value() (driver.Value, error) {
+	if p == "" {
+		return string(BillingMonthly), nil
+	}
+	return string(p), nil
+}
+
 type Subscription struct {
-	ID          string     `db:"id" json:"id"`
-	ServiceName string     `db:"service_name" json:"service_name" example:"Spotify"`
-	Price       int        `db:"price" json:"price" example:"9"`
-	UserID      string     `db:"user_id" json:"user_id" example:"550e8400-e29b-41d4-a716-446655440000"`
-	StartDate   MonthYear  `db:"start_date" json:"start_date" swaggertype:"string"`
-	EndDate     *MonthYear `db:"end_date" json:"end_date" swaggertype:"string"`
+	ID            string        `db:"id" json:"id"`
+	ServiceName   string        `db:"service_name" json:"service_name" example:"Spotify"`
+	Price         Money         `db:"price" json:"price" swaggertype:"number" example:"9.99"`
+	Currency      Currency      `db:"currency" json:"currency" swaggertype:"string" example:"RUB"`
+	UserID        string        `db:"user_id" json:"user_id" example:"550e8400-e29b-41d4-a716-446655440000"`
+	StartDate     MonthYear     `db:"start_date" json:"start_date" swaggertype:"string"`
+	EndDate       *MonthYear    `db:"end_date" json:"end_date" swaggertype:"string"`
+	BillingPeriod BillingPeriod `db:"billing_period" json:"billing_period" enums:"weekly,monthly,quarterly,annual" example:"monthly"`
+}
+
+// SubscriptionPatch — частичное обновление подписки в семантике JSON Merge Patch (RFC 7396):
+// отсутствующее поле не меняется, null сбрасывает значение (допустимо только для end_date).
+type SubscriptionPatch struct {
+	ServiceName   *string        `json:"service_name,omitempty" example:"Spotify"`
+	Price         *Money         `json:"price,omitempty" swaggertype:"number" example:"9.99"`
+	Currency      *Currency      `json:"currency,omitempty" swaggertype:"string" example:"RUB"`
+	UserID        *string        `json:"user_id,omitempty" example:"60601fee-2bf1-4721-ae6f-7636e79a0cba"`
+	StartDate     *MonthYear     `json:"start_date,omitempty" swaggertype:"string" example:"07-2025"`
+	EndDate       *MonthYear     `json:"end_date,omitempty" swaggertype:"string" example:"12-2025"`
+	EndDateSet    bool           `json:"-"`
+	BillingPeriod *BillingPeriod `json:"billing_period,omitempty" enums:"weekly,monthly,quarterly,annual" example:"monthly"`
+}
+
+func (p *SubscriptionPatch) UnmarshalJSON(data []byte) error {
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(data, &fields); err != nil || fields == nil {
+		return fmt.Errorf("merge patch must be a JSON object")
+	}
+	for name, raw := range fields {
+		isNull := string(raw) == "null"
+		if isNull && name != "end_date" {
+			return fmt.Errorf("field %s cannot be null", name)
+		}
+		var dest interface{}
+		switch name {
+		case "service_name":
+			p.ServiceName = new(string)
+			dest = p.ServiceName
+		case "price":
+			p.Price = new(Money)
+			dest = p.Price
+		case "currency":
+			p.Currency = new(Currency)
+			dest = p.Currency
+		case "user_id":
+			p.UserID = new(string)
+			dest = p.UserID
+		case "start_date":
+			p.StartDate = new(MonthYear)
+			dest = p.StartDate
+		case "end_date":
+			p.EndDateSet = true
+			if isNull {
+				p.EndDate = nil
+				continue
+			}
+			p.EndDate = new(MonthYear)
+			dest = p.EndDate
+		case "billing_period":
+			p.BillingPeriod = new(BillingPeriod)
+			dest = p.BillingPeriod
+		default:
+			return fmt.Errorf("field %s cannot be patched", name)
+		}
+		if err := json.Unmarshal(raw, dest); err != nil {
+			return fmt.Errorf("invalid %s: %v", name, err)
+		}
+	}
+	return nil
+}
+
+// ApplyTo переносит переданные в патче поля в sub.
+func (p *SubscriptionPatch) ApplyTo(sub *Subscription) {
+	if p.ServiceName != nil {
+		sub.ServiceName = *p.ServiceName
+	}
+	if p.Price != nil {
+		sub.Price = *p.Price
+	}
+	if p.Currency != nil {
+		sub.Currency = *p.Currency
+	}
+	if p.UserID != nil {
+		sub.UserID = *p.UserID
+	}
+	if p.StartDate != nil {
+		sub.StartDate = *p.StartDate
+	}
+	if p.EndDateSet {
+		sub.EndDate = p.EndDate
+	}
+	if p.BillingPeriod != nil {
+		sub.BillingPeriod = *p.BillingPeriod
+	}
+}
+
+// PriceChange — новая цена подписки, действующая начиная с месяца EffectiveFrom.
+type PriceChange struct {
+	Price         Money     `db:"price" json:"price" swaggertype:"number" example:"12.99"`
+	EffectiveFrom MonthYear `db:"effective_from" json:"effective_from" swaggertype:"string" example:"03-2025"`
+}
+
+// SubscriptionSortFields перечисляет поля, по которым можно сортировать список подписок.
+var SubscriptionSortFields = map[string]bool{
+	"id":             true,
+	"service_name":   true,
+	"price":          true,
+	"currency":       true,
+	"user_id":        true,
+	"start_date":     true,
+	"end_date":       true,
+	"billing_period": true,
+}
+
+// ListParams описывает фильтры, сортировку и пагинацию списка подписок.
+type ListParams struct {
+	Limit             int
+	Offset            int
+	UserID            string
+	ServiceName       string
+	ServiceNamePrefix string
+	PriceMin          *Money
+	PriceMax          *Money
+	ActiveAt          *time.Time
+	StartFrom         *time.Time
+	StartTo           *time.Time
+	EndFrom           *time.Time
+	EndTo             *time.Time
+	Sort              string
+	Desc              bool
+}
+
+// SubscriptionList — страница списка подписок.
+type SubscriptionList struct {
+	Items      []Subscription `json:"items"`
+	Total      int            `json:"total" example:"42"`
+	Limit      int            `json:"limit" example:"50"`
+	Offset     int            `json:"offset" example:"0"`
+	NextCursor string         `json:"next_cursor,omitempty" example:"NTA"`
+}
+
+// CostGroupByFields перечисляет поля, по которым можно группировать помесячную разбивку стоимости.
+var CostGroupByFields = map[string]bool{
+	"service_name": true,
+	"user_id":      true,
+}
+
+// CostEntry — стоимость подписок за один месяц (и, при группировке, для одного значения группы).
+type CostEntry struct {
+	Month    MonthYear `db:"month" json:"month" swaggertype:"string" example:"01-2025"`
+	Group    string    `db:"group_key" json:"group,omitempty" example:"Spotify"`
+	Currency Currency  `db:"currency" json:"-"`
+	Cost     Money     `db:"cost" json:"cost" swaggertype:"number" example:"400.00"`
+}
+
+// CostBreakdown — помесячная разбивка стоимости подписок за период.
+type CostBreakdown struct {
+	GroupBy   string      `json:"group_by,omitempty" example:"service_name"`
+	Currency  Currency    `json:"currency" swaggertype:"string" example:"RUB"`
+	Items     []CostEntry `json:"items"`
+	TotalCost Money       `json:"total_cost" swaggertype:"number" example:"4800.00"`
 }
@@ -0,0 +1,139 @@
+package models
+
+import (
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"math"
+	"math/big"
+	"regexp"
+	"strings"
+)
+
+// Money — денежная сумма с фиксированной точкой: целое число сотых долей единицы валюты
+// (копеек, центов). Арифметика над Money не использует float64, поэтому не накапливает погрешность.
+type Money int64
+
+const moneyScale = 100
+
+// ErrMoneyOverflow — результат операции не помещается в Money.
+var ErrMoneyOverflow = errors.New("money amount out of range")
+
+// decimalPattern — десятичная запись числа с необязательной экспонентой, как в JSON.
+// big.Rat.SetString понимает и дроби вида "10/4", и шестнадцатеричную запись; их отсекаем,
+// а экспоненту ограничиваем тремя цифрами, чтобы не вычислять огромные степени десяти.
+var decimalPattern = regexp.MustCompile(`^[+-]?(\d+(\.\d*)?|\.\d+)([eE][+-]?\d{1,3})?$`)
+
+func (Money) SwaggerType() []string {
+	return []string{"number"}
+}
+
+// ParseMoney разбирает десятичную запись суммы ("9.99", "400", "1e2") не более чем с двумя знаками после запятой.
+func ParseMoney(s string) (Money, error) {
+	s = strings.TrimSpace(s)
+	if !decimalPattern.MatchString(s) {
+		return 0, fmt.Errorf("invalid money amount %q", s)
+	}
+	r, ok := new(big.Rat).SetString(s)
+	if !ok {
+		return 0, fmt.Errorf("invalid money amount %q", s)
+	}
+	r.Mul(r, big.NewRat(moneyScale, 1))
+	if !r.IsInt() {
+		return 0, fmt.Errorf("invalid money amount %q: at most 2 decimal places allowed", s)
+	}
+	if !r.Num().IsInt64() {
+		return 0, fmt.Errorf("money amount %q: %w", s, ErrMoneyOverflow)
+	}
+	return Money(r.Num().Int64()), nil
+}
+
+func (m Money) String() string {
+	sign := ""
+	minor := int64(m)
+	if minor < 0 {
+		sign = "-"
+		minor = -minor
+	}
+	return fmt.Sprintf("%s%d.%02d", sign, minor/moneyScale, minor%moneyScale)
+}
+
+// Mul умножает сумму на точный коэффициент (например, курс валюты) с округлением
+// до сотых по правилу «половина от нуля». Если результат не помещается в Money, возвращается ErrMoneyOverflow.
+func (m Money) Mul(k *big.Rat) (Money, error) {
+	r := new(big.Rat).Mul(big.NewRat(int64(m), 1), k)
+	num := new(big.Int).Abs(r.Num())
+	den := r.Denom()
+	q, rem := new(big.Int).QuoRem(num, den, new(big.Int))
+	if rem.Mul(rem, big.NewInt(2)).Cmp(den) >= 0 {
+		q.Add(q, big.NewInt(1))
+	}
+	if r.Sign() < 0 {
+		q.Neg(q)
+	}
+	if !q.IsInt64() {
+		return 0, fmt.Errorf("%s * %s: %w", m, k.RatString(), ErrMoneyOverflow)
+	}
+	return Money(q.Int64()), nil
+}
+
+// Add складывает суммы. Если результат не помещается в Money, возвращается ErrMoneyOverflow.
+func (m Money) Add(other Money) (Money, error) {
+	sum := m + other
+	if (other > 0 && sum < m) || (other < 0 && sum > m) {
+		return 0, fmt.Errorf("%s + %s: %w", m, other, ErrMoneyOverflow)
+	}
+	return sum, nil
+}
+
+// UnmarshalJSON принимает сумму как числом (9.99), так и строкой ("9.99").
+func (m *Money) UnmarshalJSON(data []byte) error {
+	str := string(data)
+	if len(str) >= 2 && str[0] == '"' && str[len(str)-1] == '"' {
+		str = str[1 : len(str)-1]
+	}
+	parsed, err := ParseMoney(str)
+	if err != nil {
+		return err
+	}
+	*m = parsed
+	return nil
+}
+
+func (m Money) MarshalJSON() ([]byte, error) {
+	return []byte(m.String()), nil
+}
+
+func (m *Money) Scan(value interface{}) error {
+	switch v := value.(type) {
+	case nil:
+		*m = 0
+		return nil
+	case int64:
+		if v > math.MaxInt64/moneyScale || v < math.MinInt64/moneyScale {
+			return fmt.Errorf("scan %d: %w", v, ErrMoneyOverflow)
+		}
+		*m = Money(v * moneyScale)
+		return nil
+	case []byte:
+		parsed, err := ParseMoney(string(v))
+		if err != nil {
+			return err
+		}
+		*m = parsed
+		return nil
+	case string:
+		parsed, err := ParseMoney(v)
+		if err != nil {
+			return err
+		}
+		*m = parsed
+		return nil
+	default:
+		return fmt.Errorf("cannot scan type %T into Money", value)
+	}
+}
+
+func (m Money) Value() (driver.Value, error) {
+	return m.String(), nil
+}
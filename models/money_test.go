@@ -0,0 +1,181 @@
+package models
+
+import (
+	"errors"
+	"math"
+	"math/big"
+	"testing"
+)
+
+func TestParseMoney(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    Money
+		wantErr bool
+	}{
+		{in: "400", want: 40000},
+		{in: "9.99", want: 999},
+		{in: " 9.9 ", want: 990},
+		{in: "0.01", want: 1},
+		{in: ".5", want: 50},
+		{in: "5.", want: 500},
+		{in: "-12.30", want: -1230},
+		{in: "+1", want: 100},
+		{in: "1e2", want: 10000},
+		{in: "1.5E-1", want: 15},
+		{in: "0", want: 0},
+		{in: "9.999", wantErr: true},
+		{in: "10/4", wantErr: true},
+		{in: "1/3", wantErr: true},
+		{in: "0x10", wantErr: true},
+		{in: "0x1p-2", wantErr: true},
+		{in: "1_000", wantErr: true},
+		{in: "1e1000", wantErr: true},
+		{in: "1e100000000", wantErr: true},
+		{in: "Inf", wantErr: true},
+		{in: "", wantErr: true},
+		{in: "abc", wantErr: true},
+		{in: "1,5", wantErr: true},
+		{in: "92233720368547758.08", wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := ParseMoney(tt.in)
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("ParseMoney(%q) = %d, want error", tt.in, got)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("ParseMoney(%q) error: %v", tt.in, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("ParseMoney(%q) = %d, want %d", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestMoneyString(t *testing.T) {
+	tests := []struct {
+		in   Money
+		want string
+	}{
+		{0, "0.00"},
+		{1, "0.01"},
+		{999, "9.99"},
+		{40000, "400.00"},
+		{-5, "-0.05"},
+		{-1230, "-12.30"},
+	}
+	for _, tt := range tests {
+		if got := tt.in.String(); got != tt.want {
+			t.Errorf("Money(%d).String() = %q, want %q", int64(tt.in), got, tt.want)
+		}
+	}
+}
+
+func TestMoneyMul(t *testing.T) {
+	tests := []struct {
+		name    string
+		m       Money
+		k       *big.Rat
+		want    Money
+		wantErr bool
+	}{
+		{name: "integer", m: 1000, k: big.NewRat(3, 1), want: 3000},
+		{name: "exact fraction", m: 1000, k: big.NewRat(1, 4), want: 250},
+		{name: "round down", m: 100, k: big.NewRat(1, 3), want: 33},
+		{name: "round up", m: 200, k: big.NewRat(1, 3), want: 67},
+		{name: "half away from zero", m: 1, k: big.NewRat(1, 2), want: 1},
+		{name: "negative half away from zero", m: -1, k: big.NewRat(1, 2), want: -1},
+		{name: "negative round down", m: -100, k: big.NewRat(1, 3), want: -33},
+		{name: "exchange rate", m: 999, k: big.NewRat(9215, 100), want: 92058},
+		{name: "overflow", m: Money(math.MaxInt64 / 2), k: big.NewRat(3, 1), wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.m.Mul(tt.k)
+			if tt.wantErr {
+				if !errors.Is(err, ErrMoneyOverflow) {
+					t.Fatalf("Mul() = %d, %v, want ErrMoneyOverflow", got, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Mul() error: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("Mul() = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMoneyAdd(t *testing.T) {
+	tests := []struct {
+		name    string
+		m, o    Money
+		want    Money
+		wantErr bool
+	}{
+		{name: "positive", m: 999, o: 1, want: 1000},
+		{name: "negative", m: 100, o: -250, want: -150},
+		{name: "max", m: math.MaxInt64 - 1, o: 1, want: math.MaxInt64},
+		{name: "overflow", m: math.MaxInt64, o: 1, wantErr: true},
+		{name: "underflow", m: math.MinInt64, o: -1, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.m.Add(tt.o)
+			if tt.wantErr {
+				if !errors.Is(err, ErrMoneyOverflow) {
+					t.Fatalf("Add() = %d, %v, want ErrMoneyOverflow", got, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Add() error: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("Add() = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMoneyScan(t *testing.T) {
+	tests := []struct {
+		name    string
+		in      interface{}
+		want    Money
+		wantErr bool
+	}{
+		{name: "nil", in: nil, want: 0},
+		{name: "int64", in: int64(400), want: 40000},
+		{name: "numeric bytes", in: []byte("9.99"), want: 999},
+		{name: "numeric string", in: "12.50", want: 1250},
+		{name: "int64 overflow", in: int64(math.MaxInt64 / 10), wantErr: true},
+		{name: "fraction string", in: "10/4", wantErr: true},
+		{name: "too many decimals", in: []byte("1.005"), wantErr: true},
+		{name: "unsupported type", in: 1.5, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got Money
+			err := got.Scan(tt.in)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("Scan(%v) = %d, want error", tt.in, got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Scan(%v) error: %v", tt.in, err)
+			}
+			if got != tt.want {
+				t.Errorf("Scan(%v) = %d, want %d", tt.in, got, tt.want)
+			}
+		})
+	}
+}
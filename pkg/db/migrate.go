@@ -1,21 +1,29 @@
 package db
 
 import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
 	"github.com/Tommych123/subscription-service/service/config"
 	"github.com/golang-migrate/migrate/v4"
 	"github.com/golang-migrate/migrate/v4/database/postgres"
+	"github.com/golang-migrate/migrate/v4/source"
 	_ "github.com/golang-migrate/migrate/v4/source/file"
 	"github.com/jmoiron/sqlx"
 	"go.uber.org/zap"
+	"io/fs"
 )
 
+const migrationsSource = "file://migrations"
+
 func RunMigrations(db *sqlx.DB, cfg *config.Config, log *zap.Logger) {
 	driver, err := postgres.WithInstance(db.DB, &postgres.Config{})
 	if err != nil {
 		log.Fatal("Failed to create DB migration driver", zap.Error(err))
 	}
 	m, err := migrate.NewWithDatabaseInstance(
-		"file://migrations",
+		migrationsSource,
 		cfg.DBName,
 		driver,
 	)
@@ -27,3 +35,48 @@ func RunMigrations(db *sqlx.DB, cfg *config.Config, log *zap.Logger) {
 	}
 	log.Info("Migrations applied successfully")
 }
+
+// LatestMigrationVersion возвращает номер последней миграции из каталога migrations.
+func LatestMigrationVersion() (uint, error) {
+	src, err := source.Open(migrationsSource)
+	if err != nil {
+		return 0, err
+	}
+	defer src.Close()
+	version, err := src.First()
+	if err != nil {
+		return 0, err
+	}
+	for {
+		next, err := src.Next(version)
+		if errors.Is(err, fs.ErrNotExist) {
+			return version, nil
+		}
+		if err != nil {
+			return 0, err
+		}
+		version = next
+	}
+}
+
+// CheckMigrations проверяет по таблице golang-migrate, что схема БД имеет версию expected
+// и последняя миграция не завершилась с ошибкой (dirty).
+func CheckMigrations(ctx context.Context, db *sqlx.DB, expected uint) error {
+	var version int64
+	var dirty bool
+	query := "SELECT version, dirty FROM " + postgres.DefaultMigrationsTable + " LIMIT 1"
+	err := db.QueryRowContext(ctx, query).Scan(&version, &dirty)
+	if errors.Is(err, sql.ErrNoRows) {
+		return fmt.Errorf("no migrations applied, expected version %d", expected)
+	}
+	if err != nil {
+		return err
+	}
+	if dirty {
+		return fmt.Errorf("migration %d is dirty", version)
+	}
+	if version != int64(expected) {
+		return fmt.Errorf("schema version %d, expected %d", version, expected)
+	}
+	return nil
+}
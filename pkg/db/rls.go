@@ -0,0 +1,34 @@
+package db
+
+import (
+	"github.com/jmoiron/sqlx"
+	"go.uber.org/zap"
+)
+
+// rlsTables — таблицы с политиками арендаторов из миграции 008.
+var rlsTables = []string{"subscriptions", "subscription_prices"}
+
+// CheckTenantRLS сверяет TENANT_RLS с тем, включена ли row-level security для таблиц с данными
+// арендаторов, и предупреждает о расхождении. Сервис не меняет схему сам: RLS включает и
+// выключает администратор БД (SQL — в README).
+func CheckTenantRLS(db *sqlx.DB, enabled bool, log *zap.Logger) {
+	for _, table := range rlsTables {
+		var state struct {
+			RowSecurity   bool `db:"relrowsecurity"`
+			ForceSecurity bool `db:"relforcerowsecurity"`
+		}
+		if err := db.Get(&state, "SELECT relrowsecurity, relforcerowsecurity FROM pg_class WHERE oid = to_regclass($1)", table); err != nil {
+			log.Warn("Failed to check row-level security", zap.String("table", table), zap.Error(err))
+			continue
+		}
+		on := state.RowSecurity && state.ForceSecurity
+		switch {
+		case enabled && !on:
+			log.Warn("TENANT_RLS is set, but row-level security is not enabled and forced for the table: tenants are isolated only by query conditions",
+				zap.String("table", table), zap.Bool("row_security", state.RowSecurity), zap.Bool("force_row_security", state.ForceSecurity))
+		case !enabled && state.RowSecurity:
+			log.Warn("Row-level security is enabled for the table, but TENANT_RLS is not set: queries may see no rows",
+				zap.String("table", table), zap.Bool("force_row_security", state.ForceSecurity))
+		}
+	}
+}
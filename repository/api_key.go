@@ -0,0 +1,123 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"github.com/Tommych123/subscription-service/models"
+	"github.com/Tommych123/subscription-service/service/errs"
+	"github.com/Tommych123/subscription-service/service/tenant"
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+	"time"
+)
+
+const apiKeyColumns = "id, tenant_id, name, prefix, scopes, created_at, expires_at, last_used_at, revoked_at"
+
+// APIKeyRepository хранит API-ключи в таблице api_keys. Сами ключи не хранятся, только их хеши.
+// List и Revoke ограничены арендатором из контекста; GetByHash ищет среди всех арендаторов.
+type APIKeyRepository struct {
+	db *sqlx.DB
+}
+
+func NewAPIKeyRepository(db *sqlx.DB) *APIKeyRepository {
+	return &APIKeyRepository{db: db}
+}
+
+// apiKeyRow — строка api_keys; scopes в PostgreSQL хранятся массивом TEXT[].
+type apiKeyRow struct {
+	ID         string         `db:"id"`
+	TenantID   string         `db:"tenant_id"`
+	Name       string         `db:"name"`
+	Prefix     string         `db:"prefix"`
+	Scopes     pq.StringArray `db:"scopes"`
+	CreatedAt  time.Time      `db:"created_at"`
+	ExpiresAt  *time.Time     `db:"expires_at"`
+	LastUsedAt *time.Time     `db:"last_used_at"`
+	RevokedAt  *time.Time     `db:"revoked_at"`
+}
+
+func (row apiKeyRow) key() *models.APIKey {
+	return &models.APIKey{
+		ID:         row.ID,
+		TenantID:   row.TenantID,
+		Name:       row.Name,
+		Prefix:     row.Prefix,
+		Scopes:     []string(row.Scopes),
+		CreatedAt:  row.CreatedAt,
+		ExpiresAt:  row.ExpiresAt,
+		LastUsedAt: row.LastUsedAt,
+		RevokedAt:  row.RevokedAt,
+	}
+}
+
+func (r *APIKeyRepository) Create(ctx context.Context, key *models.APIKey, hash string) error {
+	query := "INSERT INTO api_keys (id, tenant_id, name, prefix, key_hash, scopes, created_at, expires_at) VALUES ($1, $2, $3, $4, $5, $6, $7, $8)"
+	_, err := r.db.ExecContext(ctx, query, key.ID, key.TenantID, key.Name, key.Prefix, hash, pq.StringArray(key.Scopes), key.CreatedAt, key.ExpiresAt)
+	if err != nil {
+		return mapError(ctx, err)
+	}
+	return nil
+}
+
+// GetByHash ищет ключ по хешу; отозванные и просроченные ключи тоже возвращаются.
+func (r *APIKeyRepository) GetByHash(ctx context.Context, hash string) (*models.APIKey, error) {
+	var row apiKeyRow
+	err := r.db.GetContext(ctx, &row, "SELECT "+apiKeyColumns+" FROM api_keys WHERE key_hash = $1", hash)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, fmt.Errorf("api key: %w", errs.ErrNotFound)
+		}
+		return nil, mapError(ctx, err)
+	}
+	return row.key(), nil
+}
+
+func (r *APIKeyRepository) List(ctx context.Context) ([]models.APIKey, error) {
+	query := "SELECT " + apiKeyColumns + " FROM api_keys"
+	var args []interface{}
+	if tenantID, ok := tenant.FromContext(ctx); ok {
+		query += " WHERE tenant_id = $1"
+		args = append(args, tenantID)
+	}
+	query += " ORDER BY created_at, id"
+	var rows []apiKeyRow
+	if err := r.db.SelectContext(ctx, &rows, query, args...); err != nil {
+		return nil, mapError(ctx, err)
+	}
+	keys := make([]models.APIKey, 0, len(rows))
+	for _, row := range rows {
+		keys = append(keys, *row.key())
+	}
+	return keys, nil
+}
+
+// Revoke отзывает ключ; повторный отзыв или неизвестный id возвращают errs.ErrNotFound.
+func (r *APIKeyRepository) Revoke(ctx context.Context, id string, at time.Time) error {
+	if err := checkUUID("id", id); err != nil {
+		return err
+	}
+	args := []interface{}{id, at}
+	query := "UPDATE api_keys SET revoked_at = $2 WHERE id = $1 AND revoked_at IS NULL" + tenantCond(ctx, "tenant_id", &args)
+	res, err := r.db.ExecContext(ctx, query, args...)
+	if err != nil {
+		return mapError(ctx, err)
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return fmt.Errorf("api key %s: %w", id, errs.ErrNotFound)
+	}
+	return nil
+}
+
+// Touch запоминает время последнего использования ключа.
+func (r *APIKeyRepository) Touch(ctx context.Context, id string, at time.Time) error {
+	if _, err := r.db.ExecContext(ctx, "UPDATE api_keys SET last_used_at = $2 WHERE id = $1", id, at); err != nil {
+		return mapError(ctx, err)
+	}
+	return nil
+}
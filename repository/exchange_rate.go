@@ -0,0 +1,56 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"github.com/Tommych123/subscription-service/models"
+	"github.com/jmoiron/sqlx"
+	"math/big"
+	"time"
+)
+
+// ExchangeRateRepository хранит курсы валют в таблице exchange_rates.
+// Курс действует с указанного месяца до следующей записи по той же паре валют.
+type ExchangeRateRepository struct {
+	db *sqlx.DB
+}
+
+func NewExchangeRateRepository(db *sqlx.DB) *ExchangeRateRepository {
+	return &ExchangeRateRepository{db: db}
+}
+
+// Rate возвращает курс base→quote, действующий в месяце month. Если прямого курса нет,
+// используется обратный. ok == false, если курс для пары не загружен.
+func (r *ExchangeRateRepository) Rate(ctx context.Context, base, quote models.Currency, month time.Time) (*big.Rat, bool, error) {
+	if base == quote {
+		return big.NewRat(1, 1), true, nil
+	}
+	rate, ok, err := r.lookup(ctx, base, quote, month)
+	if err != nil || ok {
+		return rate, ok, err
+	}
+	rate, ok, err = r.lookup(ctx, quote, base, month)
+	if err != nil || !ok {
+		return nil, false, err
+	}
+	return rate.Inv(rate), true, nil
+}
+
+func (r *ExchangeRateRepository) lookup(ctx context.Context, base, quote models.Currency, month time.Time) (*big.Rat, bool, error) {
+	query := "SELECT rate FROM exchange_rates WHERE base_currency = $1 AND quote_currency = $2 AND month <= $3::date ORDER BY month DESC LIMIT 1"
+	var raw string
+	err := r.db.GetContext(ctx, &raw, query, base, quote, month)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, false, nil
+		}
+		return nil, false, mapError(ctx, err)
+	}
+	rate, ok := new(big.Rat).SetString(raw)
+	if !ok {
+		return nil, false, fmt.Errorf("invalid exchange rate %q for %s/%s", raw, base, quote)
+	}
+	return rate, true, nil
+}
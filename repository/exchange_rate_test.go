@@ -0,0 +1,47 @@
+package repository
+
+import (
+	"context"
+	"github.com/Tommych123/subscription-service/models"
+	"math/big"
+	"testing"
+)
+
+func TestExchangeRate(t *testing.T) {
+	ctx := context.Background()
+	db := openTestDB(t)
+	db.MustExec(`INSERT INTO exchange_rates (base_currency, quote_currency, month, rate) VALUES
+		('USD', 'RUB', '2025-01-01', 90),
+		('USD', 'RUB', '2025-03-01', 100),
+		('EUR', 'RUB', '2025-02-01', 98)`)
+	repo := NewExchangeRateRepository(db)
+	tests := []struct {
+		name        string
+		base, quote models.Currency
+		month       string
+		want        string
+		ok          bool
+	}{
+		{name: "same currency", base: "RUB", quote: "RUB", month: "01-2020", want: "1", ok: true},
+		{name: "direct", base: "USD", quote: "RUB", month: "01-2025", want: "90", ok: true},
+		{name: "latest before month", base: "USD", quote: "RUB", month: "02-2025", want: "90", ok: true},
+		{name: "newer rate", base: "USD", quote: "RUB", month: "06-2025", want: "100", ok: true},
+		{name: "inverse", base: "RUB", quote: "USD", month: "03-2025", want: "1/100", ok: true},
+		{name: "before first rate", base: "EUR", quote: "RUB", month: "01-2025"},
+		{name: "unknown pair", base: "USD", quote: "EUR", month: "03-2025"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rate, ok, err := repo.Rate(ctx, tt.base, tt.quote, month(t, tt.month).Time)
+			if err != nil {
+				t.Fatalf("Rate() error: %v", err)
+			}
+			if ok != tt.ok {
+				t.Fatalf("Rate() ok = %v, want %v", ok, tt.ok)
+			}
+			if want, _ := new(big.Rat).SetString(tt.want); ok && rate.Cmp(want) != 0 {
+				t.Errorf("Rate() = %s, want %s", rate.RatString(), tt.want)
+			}
+		})
+	}
+}
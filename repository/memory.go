@@ -0,0 +1,481 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"github.com/Tommych123/subscription-service/models"
+	"github.com/Tommych123/subscription-service/service/tenant"
+	"github.com/google/uuid"
+	"math/big"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// MemorySubscriptionRepository — потокобезопасное хранилище подписок в памяти процесса
+// с той же семантикой, что и SubscriptionRepository. Используется для локальной разработки и тестов.
+type MemorySubscriptionRepository struct {
+	mu     sync.RWMutex
+	subs   map[string]models.Subscription
+	prices map[string][]models.PriceChange
+	// tenants — арендатор каждой подписки по её id.
+	tenants map[string]string
+	now     func() time.Time
+}
+
+func NewMemorySubscriptionRepository() *MemorySubscriptionRepository {
+	return &MemorySubscriptionRepository{
+		subs:    make(map[string]models.Subscription),
+		prices:  make(map[string][]models.PriceChange),
+		tenants: make(map[string]string),
+		now:     time.Now,
+	}
+}
+
+func (r *MemorySubscriptionRepository) Create(ctx context.Context, sub *models.Subscription) (string, error) {
+	if err := checkUUID("user_id", sub.UserID); err != nil {
+		return "", err
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	stored := normalize(*sub)
+	stored.ID = uuid.New().String()
+	tenantID, ok := tenant.FromContext(ctx)
+	if !ok {
+		tenantID = tenant.Default
+	}
+	r.subs[stored.ID] = stored
+	r.tenants[stored.ID] = tenantID
+	return stored.ID, nil
+}
+
+func (r *MemorySubscriptionRepository) GetByID(ctx context.Context, id string) (*models.Subscription, error) {
+	if err := checkUUID("id", id); err != nil {
+		return nil, err
+	}
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	sub, ok := r.lookup(ctx, id)
+	if !ok {
+		return nil, notFound(id)
+	}
+	return r.current(sub), nil
+}
+
+func (r *MemorySubscriptionRepository) Update(ctx context.Context, sub *models.Subscription) error {
+	if err := checkUUID("id", sub.ID); err != nil {
+		return err
+	}
+	if err := checkUUID("user_id", sub.UserID); err != nil {
+		return err
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	stored, ok := r.lookup(ctx, sub.ID)
+	if !ok {
+		return notFound(sub.ID)
+	}
+	updated := normalize(*sub)
+	updated.Price = stored.Price
+	r.subs[sub.ID] = updated
+	return nil
+}
+
+// Patch под блокировкой хранилища применяет patch, проверяет результат функцией check
+// и сохраняет его, не меняя исходную цену.
+func (r *MemorySubscriptionRepository) Patch(ctx context.Context, id string, patch *models.SubscriptionPatch, check func(current, next *models.Subscription) error) (*models.Subscription, error) {
+	if err := checkUUID("id", id); err != nil {
+		return nil, err
+	}
+	if patch.UserID != nil {
+		if err := checkUUID("user_id", *patch.UserID); err != nil {
+			return nil, err
+		}
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	sub, ok := r.lookup(ctx, id)
+	if !ok {
+		return nil, notFound(id)
+	}
+	current := r.current(sub)
+	next := normalize(*current)
+	patch.ApplyTo(&next)
+	next = normalize(next)
+	if err := check(current, &next); err != nil {
+		return nil, err
+	}
+	stored := next
+	stored.Price = sub.Price
+	r.subs[id] = *clone(stored)
+	return clone(next), nil
+}
+
+func (r *MemorySubscriptionRepository) Delete(ctx context.Context, id string) error {
+	if err := checkUUID("id", id); err != nil {
+		return err
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, ok := r.lookup(ctx, id); !ok {
+		return notFound(id)
+	}
+	delete(r.subs, id)
+	delete(r.prices, id)
+	delete(r.tenants, id)
+	return nil
+}
+
+func (r *MemorySubscriptionRepository) SchedulePrice(ctx context.Context, id string, change models.PriceChange) error {
+	if err := checkUUID("id", id); err != nil {
+		return err
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, ok := r.lookup(ctx, id); !ok {
+		return notFound(id)
+	}
+	changes := r.prices[id]
+	for i, existing := range changes {
+		if existing.EffectiveFrom.Equal(change.EffectiveFrom.Time) {
+			changes[i].Price = change.Price
+			return nil
+		}
+	}
+	changes = append(changes, change)
+	sort.Slice(changes, func(i, j int) bool {
+		return changes[i].EffectiveFrom.Before(changes[j].EffectiveFrom.Time)
+	})
+	r.prices[id] = changes
+	return nil
+}
+
+func (r *MemorySubscriptionRepository) List(ctx context.Context, params models.ListParams) ([]models.Subscription, int, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, 0, err
+	}
+	if params.UserID != "" {
+		if err := checkUUID("user_id", params.UserID); err != nil {
+			return nil, 0, err
+		}
+	}
+	r.mu.RLock()
+	matched := []models.Subscription{}
+	for id, sub := range r.subs {
+		if !r.inTenant(ctx, id) {
+			continue
+		}
+		if current := r.current(sub); matchesList(*current, params) {
+			matched = append(matched, *current)
+		}
+	}
+	r.mu.RUnlock()
+
+	sortColumn := "id"
+	if models.SubscriptionSortFields[params.Sort] {
+		sortColumn = params.Sort
+	}
+	sort.Slice(matched, func(i, j int) bool {
+		c := compareColumn(matched[i], matched[j], sortColumn)
+		if c == 0 {
+			c = strings.Compare(matched[i].ID, matched[j].ID)
+		}
+		if params.Desc {
+			return c > 0
+		}
+		return c < 0
+	})
+
+	total := len(matched)
+	start := min(params.Offset, total)
+	end := min(start+params.Limit, total)
+	return matched[start:end], total, nil
+}
+
+// CostBreakdown повторяет семантику chargesQuery: списания с периодичностью billing_period
+// от start_date до конца месяца end_date (или до текущей даты) по цене, действующей на дату списания.
+func (r *MemorySubscriptionRepository) CostBreakdown(ctx context.Context, userID, serviceName string, from, to time.Time, groupBy string) ([]models.CostEntry, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	if userID != "" {
+		if err := checkUUID("user_id", userID); err != nil {
+			return nil, err
+		}
+	}
+	type key struct {
+		month    time.Time
+		currency models.Currency
+		group    string
+	}
+	today := truncateDay(r.now())
+	from, to = truncateDay(from), truncateDay(to)
+	sums := make(map[key]models.Money)
+
+	r.mu.RLock()
+	for id, sub := range r.subs {
+		if !r.inTenant(ctx, id) || (userID != "" && sub.UserID != userID) || (serviceName != "" && sub.ServiceName != serviceName) {
+			continue
+		}
+		start := truncateDay(sub.StartDate.Time)
+		end := today
+		if sub.EndDate != nil {
+			end = truncateDay(sub.EndDate.Time)
+		}
+		if end.Before(from) || start.After(to) {
+			continue
+		}
+		upper := endOfMonth(to)
+		if sub.EndDate != nil {
+			upper = minTime(upper, endOfMonth(end))
+		} else {
+			upper = minTime(upper, today)
+		}
+		group := ""
+		switch groupBy {
+		case "service_name":
+			group = sub.ServiceName
+		case "user_id":
+			group = sub.UserID
+		}
+		for charged := start; !charged.After(upper); charged = nextCharge(charged, sub.BillingPeriod) {
+			if charged.Before(from) {
+				continue
+			}
+			k := key{month: time.Date(charged.Year(), charged.Month(), 1, 0, 0, 0, 0, time.UTC), currency: sub.Currency, group: group}
+			sum, err := sums[k].Add(r.priceAt(sub, charged))
+			if err != nil {
+				r.mu.RUnlock()
+				return nil, err
+			}
+			sums[k] = sum
+		}
+	}
+	r.mu.RUnlock()
+
+	entries := make([]models.CostEntry, 0, len(sums))
+	for k, cost := range sums {
+		entries = append(entries, models.CostEntry{Month: models.MonthYear{Time: k.month}, Group: k.group, Currency: k.currency, Cost: cost})
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		a, b := entries[i], entries[j]
+		if !a.Month.Equal(b.Month.Time) {
+			return a.Month.Before(b.Month.Time)
+		}
+		if a.Group != b.Group {
+			return a.Group < b.Group
+		}
+		return a.Currency < b.Currency
+	})
+	return entries, nil
+}
+
+// current возвращает копию подписки с ценой, действующей в текущем месяце.
+func (r *MemorySubscriptionRepository) current(sub models.Subscription) *models.Subscription {
+	cur := clone(sub)
+	cur.Price = r.priceAt(sub, truncateDay(r.now()))
+	return cur
+}
+
+// lookup возвращает подписку по id, если она принадлежит арендатору из ctx. Вызывается под r.mu.
+func (r *MemorySubscriptionRepository) lookup(ctx context.Context, id string) (models.Subscription, bool) {
+	sub, ok := r.subs[id]
+	if !ok || !r.inTenant(ctx, id) {
+		return models.Subscription{}, false
+	}
+	return sub, true
+}
+
+// inTenant сообщает, видна ли подписка арендатору из ctx; без арендатора видны все подписки.
+func (r *MemorySubscriptionRepository) inTenant(ctx context.Context, id string) bool {
+	tenantID, ok := tenant.FromContext(ctx)
+	return !ok || r.tenants[id] == tenantID
+}
+
+func (r *MemorySubscriptionRepository) priceAt(sub models.Subscription, at time.Time) models.Money {
+	price := sub.Price
+	for _, change := range r.prices[sub.ID] {
+		if change.EffectiveFrom.After(at) {
+			break
+		}
+		price = change.Price
+	}
+	return price
+}
+
+// MemoryExchangeRateRepository — курсы валют в памяти процесса с той же семантикой, что и ExchangeRateRepository.
+type MemoryExchangeRateRepository struct {
+	mu    sync.RWMutex
+	rates map[[2]models.Currency][]memoryRate
+}
+
+type memoryRate struct {
+	month time.Time
+	rate  *big.Rat
+}
+
+func NewMemoryExchangeRateRepository() *MemoryExchangeRateRepository {
+	return &MemoryExchangeRateRepository{rates: make(map[[2]models.Currency][]memoryRate)}
+}
+
+// SetRate загружает курс base→quote, действующий с месяца month.
+func (r *MemoryExchangeRateRepository) SetRate(base, quote models.Currency, month time.Time, rate *big.Rat) error {
+	if rate.Sign() <= 0 {
+		return fmt.Errorf("exchange rate must be positive, got %s", rate.RatString())
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	pair := [2]models.Currency{base, quote}
+	month = truncateDay(month)
+	list := r.rates[pair]
+	for i, existing := range list {
+		if existing.month.Equal(month) {
+			list[i].rate = new(big.Rat).Set(rate)
+			return nil
+		}
+	}
+	list = append(list, memoryRate{month: month, rate: new(big.Rat).Set(rate)})
+	sort.Slice(list, func(i, j int) bool { return list[i].month.Before(list[j].month) })
+	r.rates[pair] = list
+	return nil
+}
+
+func (r *MemoryExchangeRateRepository) Rate(ctx context.Context, base, quote models.Currency, month time.Time) (*big.Rat, bool, error) {
+	if base == quote {
+		return big.NewRat(1, 1), true, nil
+	}
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	if rate := r.lookup(base, quote, month); rate != nil {
+		return rate, true, nil
+	}
+	if rate := r.lookup(quote, base, month); rate != nil {
+		return rate.Inv(rate), true, nil
+	}
+	return nil, false, nil
+}
+
+func (r *MemoryExchangeRateRepository) lookup(base, quote models.Currency, month time.Time) *big.Rat {
+	var found *big.Rat
+	for _, entry := range r.rates[[2]models.Currency{base, quote}] {
+		if entry.month.After(month) {
+			break
+		}
+		found = entry.rate
+	}
+	if found == nil {
+		return nil
+	}
+	return new(big.Rat).Set(found)
+}
+
+func matchesList(sub models.Subscription, p models.ListParams) bool {
+	switch {
+	case p.UserID != "" && sub.UserID != p.UserID,
+		p.ServiceName != "" && sub.ServiceName != p.ServiceName,
+		p.ServiceNamePrefix != "" && !strings.HasPrefix(sub.ServiceName, p.ServiceNamePrefix),
+		p.PriceMin != nil && sub.Price < *p.PriceMin,
+		p.PriceMax != nil && sub.Price > *p.PriceMax,
+		p.StartFrom != nil && sub.StartDate.Before(*p.StartFrom),
+		p.StartTo != nil && sub.StartDate.After(*p.StartTo):
+		return false
+	}
+	if p.ActiveAt != nil && (sub.StartDate.After(*p.ActiveAt) || sub.EndDate != nil && sub.EndDate.Before(*p.ActiveAt)) {
+		return false
+	}
+	if p.EndFrom != nil && (sub.EndDate == nil || sub.EndDate.Before(*p.EndFrom)) {
+		return false
+	}
+	if p.EndTo != nil && (sub.EndDate == nil || sub.EndDate.After(*p.EndTo)) {
+		return false
+	}
+	return true
+}
+
+// compareColumn сравнивает подписки по колонке; пустой end_date, как и в PostgreSQL, считается наибольшим.
+func compareColumn(a, b models.Subscription, column string) int {
+	switch column {
+	case "service_name":
+		return strings.Compare(a.ServiceName, b.ServiceName)
+	case "price":
+		return compareInt(int64(a.Price), int64(b.Price))
+	case "currency":
+		return strings.Compare(string(a.Currency), string(b.Currency))
+	case "user_id":
+		return strings.Compare(a.UserID, b.UserID)
+	case "start_date":
+		return a.StartDate.Compare(b.StartDate.Time)
+	case "end_date":
+		switch {
+		case a.EndDate == nil && b.EndDate == nil:
+			return 0
+		case a.EndDate == nil:
+			return 1
+		case b.EndDate == nil:
+			return -1
+		}
+		return a.EndDate.Compare(b.EndDate.Time)
+	case "billing_period":
+		return strings.Compare(string(a.BillingPeriod), string(b.BillingPeriod))
+	default:
+		return strings.Compare(a.ID, b.ID)
+	}
+}
+
+func compareInt(a, b int64) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
+
+// normalize подставляет значения по умолчанию так же, как это делают DEFAULT-ограничения таблицы.
+func normalize(sub models.Subscription) models.Subscription {
+	if sub.Currency == "" {
+		sub.Currency = models.DefaultCurrency
+	}
+	if sub.BillingPeriod == "" {
+		sub.BillingPeriod = models.BillingMonthly
+	}
+	return *clone(sub)
+}
+
+func clone(sub models.Subscription) *models.Subscription {
+	if sub.EndDate != nil {
+		end := *sub.EndDate
+		sub.EndDate = &end
+	}
+	return &sub
+}
+
+func nextCharge(t time.Time, period models.BillingPeriod) time.Time {
+	switch period {
+	case models.BillingWeekly:
+		return t.AddDate(0, 0, 7)
+	case models.BillingQuarterly:
+		return t.AddDate(0, 3, 0)
+	case models.BillingAnnual:
+		return t.AddDate(1, 0, 0)
+	default:
+		return t.AddDate(0, 1, 0)
+	}
+}
+
+func truncateDay(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+}
+
+func endOfMonth(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, time.UTC).AddDate(0, 1, -1)
+}
+
+func minTime(a, b time.Time) time.Time {
+	if a.Before(b) {
+		return a
+	}
+	return b
+}
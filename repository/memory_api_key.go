@@ -0,0 +1,101 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"github.com/Tommych123/subscription-service/models"
+	"github.com/Tommych123/subscription-service/service/errs"
+	"github.com/Tommych123/subscription-service/service/tenant"
+	"slices"
+	"sort"
+	"sync"
+	"time"
+)
+
+// MemoryAPIKeyRepository — хранилище API-ключей в памяти процесса с той же семантикой, что и APIKeyRepository.
+type MemoryAPIKeyRepository struct {
+	mu     sync.RWMutex
+	keys   map[string]models.APIKey
+	hashes map[string]string
+}
+
+func NewMemoryAPIKeyRepository() *MemoryAPIKeyRepository {
+	return &MemoryAPIKeyRepository{
+		keys:   make(map[string]models.APIKey),
+		hashes: make(map[string]string),
+	}
+}
+
+func (r *MemoryAPIKeyRepository) Create(ctx context.Context, key *models.APIKey, hash string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, ok := r.hashes[hash]; ok {
+		return fmt.Errorf("api key hash: %w", errs.ErrConflict)
+	}
+	r.keys[key.ID] = cloneAPIKey(*key)
+	r.hashes[hash] = key.ID
+	return nil
+}
+
+func (r *MemoryAPIKeyRepository) GetByHash(ctx context.Context, hash string) (*models.APIKey, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	id, ok := r.hashes[hash]
+	if !ok {
+		return nil, fmt.Errorf("api key: %w", errs.ErrNotFound)
+	}
+	key := cloneAPIKey(r.keys[id])
+	return &key, nil
+}
+
+func (r *MemoryAPIKeyRepository) List(ctx context.Context) ([]models.APIKey, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	keys := make([]models.APIKey, 0, len(r.keys))
+	tenantID, scoped := tenant.FromContext(ctx)
+	for _, key := range r.keys {
+		if !scoped || key.TenantID == tenantID {
+			keys = append(keys, cloneAPIKey(key))
+		}
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if !keys[i].CreatedAt.Equal(keys[j].CreatedAt) {
+			return keys[i].CreatedAt.Before(keys[j].CreatedAt)
+		}
+		return keys[i].ID < keys[j].ID
+	})
+	return keys, nil
+}
+
+func (r *MemoryAPIKeyRepository) Revoke(ctx context.Context, id string, at time.Time) error {
+	if err := checkUUID("id", id); err != nil {
+		return err
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	key, ok := r.keys[id]
+	if tenantID, scoped := tenant.FromContext(ctx); scoped && key.TenantID != tenantID {
+		ok = false
+	}
+	if !ok || key.RevokedAt != nil {
+		return fmt.Errorf("api key %s: %w", id, errs.ErrNotFound)
+	}
+	key.RevokedAt = &at
+	r.keys[id] = key
+	return nil
+}
+
+func (r *MemoryAPIKeyRepository) Touch(ctx context.Context, id string, at time.Time) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if key, ok := r.keys[id]; ok {
+		key.LastUsedAt = &at
+		r.keys[id] = key
+	}
+	return nil
+}
+
+func cloneAPIKey(key models.APIKey) models.APIKey {
+	key.Scopes = slices.Clone(key.Scopes)
+	return key
+}
@@ -0,0 +1,95 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"github.com/Tommych123/subscription-service/models"
+	"github.com/Tommych123/subscription-service/service/errs"
+	"github.com/Tommych123/subscription-service/service/tenant"
+	"testing"
+	"time"
+)
+
+func TestMemoryCurrentPrice(t *testing.T) {
+	ctx := context.Background()
+	repo := NewMemorySubscriptionRepository()
+	repo.now = func() time.Time { return time.Date(2025, 3, 15, 12, 0, 0, 0, time.UTC) }
+	id, err := repo.Create(ctx, &models.Subscription{ServiceName: "Spotify", Price: 10000, Currency: models.DefaultCurrency, UserID: alice, StartDate: month(t, "01-2025"), BillingPeriod: models.BillingMonthly})
+	if err != nil {
+		t.Fatalf("Create() error: %v", err)
+	}
+	for _, change := range []models.PriceChange{
+		{Price: 20000, EffectiveFrom: month(t, "03-2025")},
+		{Price: 30000, EffectiveFrom: month(t, "06-2025")},
+	} {
+		if err := repo.SchedulePrice(ctx, id, change); err != nil {
+			t.Fatalf("SchedulePrice(%+v) error: %v", change, err)
+		}
+	}
+
+	sub, err := repo.GetByID(ctx, id)
+	if err != nil {
+		t.Fatalf("GetByID() error: %v", err)
+	}
+	if sub.Price != 20000 {
+		t.Errorf("GetByID() price = %s, want current 200.00", sub.Price)
+	}
+	priceMin := models.Money(15000)
+	list, total, err := repo.List(ctx, models.ListParams{Limit: 50, PriceMin: &priceMin})
+	if err != nil {
+		t.Fatalf("List() error: %v", err)
+	}
+	if total != 1 || list[0].Price != 20000 {
+		t.Errorf("List(price_min=150.00) = %+v, want the subscription with current price", list)
+	}
+
+	sub.ServiceName = "Spotify Family"
+	if err := repo.Update(ctx, sub); err != nil {
+		t.Fatalf("Update() error: %v", err)
+	}
+	if base := repo.subs[id].Price; base != 10000 {
+		t.Errorf("base price after Update = %s, want 100.00", base)
+	}
+
+	// Отклонённый patch ничего не сохраняет.
+	name := "Rejected"
+	reject := errors.New("rejected")
+	if _, err := repo.Patch(ctx, id, &models.SubscriptionPatch{ServiceName: &name}, func(current, next *models.Subscription) error {
+		if current.Price != 20000 || next.ServiceName != name {
+			t.Errorf("check(current=%+v, next=%+v), want current price and patched name", current, next)
+		}
+		return reject
+	}); !errors.Is(err, reject) {
+		t.Errorf("Patch() error = %v, want check error", err)
+	}
+	if stored := repo.subs[id]; stored.ServiceName != "Spotify Family" || stored.Price != 10000 {
+		t.Errorf("rejected patch changed subscription to %+v", stored)
+	}
+	if _, err := repo.Patch(ctx, missing, &models.SubscriptionPatch{ServiceName: &name}, func(_, _ *models.Subscription) error { return nil }); !errors.Is(err, errs.ErrNotFound) {
+		t.Errorf("Patch(missing) error = %v, want ErrNotFound", err)
+	}
+}
+
+func TestMemoryTenantIsolation(t *testing.T) {
+	repo := NewMemorySubscriptionRepository()
+	acme, globex := tenant.WithTenant(context.Background(), "acme"), tenant.WithTenant(context.Background(), "globex")
+	id, err := repo.Create(acme, &models.Subscription{ServiceName: "Spotify", Price: 10000, Currency: models.DefaultCurrency, UserID: alice, StartDate: month(t, "01-2025"), BillingPeriod: models.BillingMonthly})
+	if err != nil {
+		t.Fatalf("Create() error: %v", err)
+	}
+	if _, err := repo.GetByID(globex, id); !errors.Is(err, errs.ErrNotFound) {
+		t.Errorf("GetByID(other tenant) error = %v, want ErrNotFound", err)
+	}
+	if err := repo.Delete(globex, id); !errors.Is(err, errs.ErrNotFound) {
+		t.Errorf("Delete(other tenant) error = %v, want ErrNotFound", err)
+	}
+	if _, total, err := repo.List(globex, models.ListParams{Limit: 10}); err != nil || total != 0 {
+		t.Errorf("List(other tenant) total = %d, %v, want 0", total, err)
+	}
+	if _, total, err := repo.List(acme, models.ListParams{Limit: 10}); err != nil || total != 1 {
+		t.Errorf("List(own tenant) total = %d, %v, want 1", total, err)
+	}
+	if _, total, err := repo.List(context.Background(), models.ListParams{Limit: 10}); err != nil || total != 1 {
+		t.Errorf("List(without tenant) total = %d, %v, want 1", total, err)
+	}
+}
@@ -1,63 +1,462 @@
 package repository
 
 import (
+	"context"
 	"database/sql"
 	"errors"
+	"fmt"
 	"github.com/Tommych123/subscription-service/models"
+	"github.com/Tommych123/subscription-service/service/errs"
+	"github.com/Tommych123/subscription-service/service/tenant"
 	"github.com/google/uuid"
 	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+	"go.opentelemetry.io/otel/attribute"
+	"strings"
+	"time"
 )
 
+const subscriptionColumns = "id, service_name, price, currency, user_id, start_date, end_date, billing_period"
+
+// currentPrice — цена подписки s, действующая в текущем месяце: последняя из subscription_prices,
+// вступившая в силу к текущей дате, либо исходная price.
+const currentPrice = `COALESCE((
+		SELECT p.price FROM subscription_prices p
+		WHERE p.subscription_id = s.id AND p.effective_from <= CURRENT_DATE
+		ORDER BY p.effective_from DESC LIMIT 1
+	), s.price)`
+
+// currentColumns — поля подписки s с ценой текущего месяца.
+const currentColumns = "s.id, s.service_name, " + currentPrice + " AS price, s.currency, s.user_id, s.start_date, s.end_date, s.billing_period"
+
+// currentSubscriptions — подписки с ценой текущего месяца и арендатором.
+const currentSubscriptions = "(SELECT " + currentColumns + ", s.tenant_id FROM subscriptions s) subscriptions"
+
+// SubscriptionRepository хранит подписки в PostgreSQL. Все запросы ограничены арендатором
+// из контекста. При rls запрос дополнительно выполняется в транзакции с переменной сеанса
+// app.tenant_id (или app.all_tenants для tenant.WithAllTenants), по которой арендатора
+// проверяют политики row-level security.
 type SubscriptionRepository struct {
-	db *sqlx.DB
+	db  *sqlx.DB
+	rls bool
 }
 
-func NewSubscriptionRepository(db *sqlx.DB) *SubscriptionRepository {
-	return &SubscriptionRepository{db: db}
+func NewSubscriptionRepository(db *sqlx.DB, rls bool) *SubscriptionRepository {
+	return &SubscriptionRepository{db: db, rls: rls}
 }
 
-func (r *SubscriptionRepository) Create(sub *models.Subscription) (string, error) {
-	id := uuid.New().String()
-	query := "INSERT INTO subscriptions (id, service_name, price, user_id, start_date, end_date) VALUES (:id, :service_name, :price, :user_id, :start_date, :end_date)"
-	subWithID := *sub
-	subWithID.ID = id
-	_, err := r.db.NamedExec(query, subWithID)
+// run выполняет fn на пуле соединений, а при включённой RLS — в транзакции из begin.
+func (r *SubscriptionRepository) run(ctx context.Context, fn func(q sqlx.ExtContext) error) error {
+	if !r.rls {
+		return fn(r.db)
+	}
+	tx, err := r.begin(ctx)
 	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	if err := fn(tx); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// begin начинает транзакцию. При включённой RLS в ней задаётся app.tenant_id арендатора запроса
+// или app.all_tenants; без них политики не пропускают ни одной строки, поэтому запрос без
+// арендатора при RLS ничего не находит.
+func (r *SubscriptionRepository) begin(ctx context.Context) (*sqlx.Tx, error) {
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil || !r.rls {
+		return tx, err
+	}
+	setting, value := "app.tenant_id", ""
+	if tenantID, ok := tenant.FromContext(ctx); ok {
+		value = tenantID
+	} else if tenant.AllTenants(ctx) {
+		setting, value = "app.all_tenants", "on"
+	} else {
+		return tx, nil
+	}
+	if _, err := tx.ExecContext(ctx, "SELECT set_config($1, $2, true)", setting, value); err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+	return tx, nil
+}
+
+func (r *SubscriptionRepository) Create(ctx context.Context, sub *models.Subscription) (id string, err error) {
+	ctx, span := startSpan(ctx, "SubscriptionRepository.Create", attribute.String("user_id", sub.UserID), attribute.String("service_name", sub.ServiceName))
+	defer func() { endSpan(span, err) }()
+	if err := checkUUID("user_id", sub.UserID); err != nil {
 		return "", err
 	}
+	id = uuid.New().String()
+	tenantID, ok := tenant.FromContext(ctx)
+	if !ok {
+		tenantID = tenant.Default
+	}
+	query := "INSERT INTO subscriptions (id, tenant_id, service_name, price, currency, user_id, start_date, end_date, billing_period) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)"
+	err = r.run(ctx, func(q sqlx.ExtContext) error {
+		_, err := q.ExecContext(ctx, query, id, tenantID, sub.ServiceName, sub.Price, sub.Currency, sub.UserID, sub.StartDate, sub.EndDate, sub.BillingPeriod)
+		return err
+	})
+	if err != nil {
+		return "", mapError(ctx, err)
+	}
 	return id, nil
 }
 
-func (r *SubscriptionRepository) GetByID(id string) (*models.Subscription, error) {
-	query := "SELECT id, service_name, price, user_id, start_date, end_date FROM subscriptions WHERE id = $1"
+func (r *SubscriptionRepository) GetByID(ctx context.Context, id string) (_ *models.Subscription, err error) {
+	ctx, span := startSpan(ctx, "SubscriptionRepository.GetByID", attribute.String("subscription_id", id))
+	defer func() { endSpan(span, err) }()
+	if err := checkUUID("id", id); err != nil {
+		return nil, err
+	}
+	args := []interface{}{id}
+	query := "SELECT " + subscriptionColumns + " FROM " + currentSubscriptions + " WHERE id = $1" + tenantCond(ctx, "tenant_id", &args)
 	var sub models.Subscription
-	err := r.db.Get(&sub, query, id)
+	err = r.run(ctx, func(q sqlx.ExtContext) error {
+		return sqlx.GetContext(ctx, q, &sub, query, args...)
+	})
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
-			return nil, nil
+			return nil, notFound(id)
 		}
-		return nil, err
+		return nil, mapError(ctx, err)
 	}
 	return &sub, nil
 }
 
-func (r *SubscriptionRepository) Update(sub *models.Subscription) error {
-	query := "UPDATE subscriptions SET service_name = :service_name, price = :price, user_id = :user_id, start_date = :start_date, end_date = :end_date WHERE id = :id"
-	_, err := r.db.NamedExec(query, sub)
-	return err
+// Update обновляет поля подписки, кроме цены: она меняется только через SchedulePrice.
+func (r *SubscriptionRepository) Update(ctx context.Context, sub *models.Subscription) (err error) {
+	ctx, span := startSpan(ctx, "SubscriptionRepository.Update", attribute.String("subscription_id", sub.ID), attribute.String("user_id", sub.UserID), attribute.String("service_name", sub.ServiceName))
+	defer func() { endSpan(span, err) }()
+	if err := checkUUID("id", sub.ID); err != nil {
+		return err
+	}
+	if err := checkUUID("user_id", sub.UserID); err != nil {
+		return err
+	}
+	args := []interface{}{sub.ID, sub.ServiceName, sub.Currency, sub.UserID, sub.StartDate, sub.EndDate, sub.BillingPeriod}
+	query := "UPDATE subscriptions SET service_name = $2, currency = $3, user_id = $4, start_date = $5, end_date = $6, billing_period = $7 WHERE id = $1" + tenantCond(ctx, "tenant_id", &args)
+	return r.execAffected(ctx, sub.ID, query, args...)
 }
 
-func (r *SubscriptionRepository) Delete(id string) error {
-	_, err := r.db.Exec("DELETE FROM subscriptions WHERE id = $1", id)
-	return err
+// Patch в одной транзакции блокирует подписку, применяет к ней patch и проверяет результат
+// функцией check, после чего сохраняет переданные поля, кроме цены: она меняется только через
+// SchedulePrice. Возвращает подписку после изменения.
+func (r *SubscriptionRepository) Patch(ctx context.Context, id string, patch *models.SubscriptionPatch, check func(current, next *models.Subscription) error) (_ *models.Subscription, err error) {
+	ctx, span := startSpan(ctx, "SubscriptionRepository.Patch", attribute.String("subscription_id", id))
+	defer func() { endSpan(span, err) }()
+	if err := checkUUID("id", id); err != nil {
+		return nil, err
+	}
+	if patch.UserID != nil {
+		if err := checkUUID("user_id", *patch.UserID); err != nil {
+			return nil, err
+		}
+	}
+	tx, err := r.begin(ctx)
+	if err != nil {
+		return nil, mapError(ctx, err)
+	}
+	defer tx.Rollback()
+
+	lockArgs := []interface{}{id}
+	lock := "SELECT " + currentColumns + " FROM subscriptions s WHERE s.id = $1" + tenantCond(ctx, "s.tenant_id", &lockArgs) + " FOR UPDATE OF s"
+	var current models.Subscription
+	if err := tx.GetContext(ctx, &current, lock, lockArgs...); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, notFound(id)
+		}
+		return nil, mapError(ctx, err)
+	}
+	next := current
+	patch.ApplyTo(&next)
+	if err := check(&current, &next); err != nil {
+		return nil, err
+	}
+
+	var sets []string
+	var args []interface{}
+	set := func(column string, value interface{}) {
+		args = append(args, value)
+		sets = append(sets, fmt.Sprintf("%s = $%d", column, len(args)))
+	}
+	if patch.ServiceName != nil {
+		set("service_name", next.ServiceName)
+	}
+	if patch.Currency != nil {
+		set("currency", next.Currency)
+	}
+	if patch.UserID != nil {
+		set("user_id", next.UserID)
+	}
+	if patch.StartDate != nil {
+		set("start_date", next.StartDate)
+	}
+	if patch.EndDateSet {
+		set("end_date", next.EndDate)
+	}
+	if patch.BillingPeriod != nil {
+		set("billing_period", next.BillingPeriod)
+	}
+	if len(sets) > 0 {
+		args = append(args, id)
+		query := fmt.Sprintf("UPDATE subscriptions SET %s WHERE id = $%d", strings.Join(sets, ", "), len(args))
+		if _, err := tx.ExecContext(ctx, query, args...); err != nil {
+			return nil, mapError(ctx, err)
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, mapError(ctx, err)
+	}
+	return &next, nil
+}
+
+func (r *SubscriptionRepository) Delete(ctx context.Context, id string) (err error) {
+	ctx, span := startSpan(ctx, "SubscriptionRepository.Delete", attribute.String("subscription_id", id))
+	defer func() { endSpan(span, err) }()
+	if err := checkUUID("id", id); err != nil {
+		return err
+	}
+	args := []interface{}{id}
+	return r.execAffected(ctx, id, "DELETE FROM subscriptions WHERE id = $1"+tenantCond(ctx, "tenant_id", &args), args...)
+}
+
+// SchedulePrice задаёт цену подписки, действующую с месяца change.EffectiveFrom.
+func (r *SubscriptionRepository) SchedulePrice(ctx context.Context, id string, change models.PriceChange) (err error) {
+	ctx, span := startSpan(ctx, "SubscriptionRepository.SchedulePrice", attribute.String("subscription_id", id))
+	defer func() { endSpan(span, err) }()
+	if err := checkUUID("id", id); err != nil {
+		return err
+	}
+	args := []interface{}{id, change.EffectiveFrom, change.Price}
+	query := `INSERT INTO subscription_prices (subscription_id, effective_from, price)
+SELECT id, $2::date, $3 FROM subscriptions WHERE id = $1` + tenantCond(ctx, "tenant_id", &args) + `
+ON CONFLICT (subscription_id, effective_from) DO UPDATE SET price = EXCLUDED.price`
+	return r.execAffected(ctx, id, query, args...)
+}
+
+// List возвращает страницу подписок; фильтр и сортировка по цене учитывают цену текущего месяца.
+func (r *SubscriptionRepository) List(ctx context.Context, params models.ListParams) (_ []models.Subscription, _ int, err error) {
+	ctx, span := startSpan(ctx, "SubscriptionRepository.List", attribute.String("user_id", params.UserID), attribute.String("service_name", params.ServiceName))
+	defer func() { endSpan(span, err) }()
+	if params.UserID != "" {
+		if err := checkUUID("user_id", params.UserID); err != nil {
+			return nil, 0, err
+		}
+	}
+	var conds []string
+	var args []interface{}
+	where := func(cond string, arg interface{}) {
+		args = append(args, arg)
+		conds = append(conds, fmt.Sprintf(cond, len(args)))
+	}
+	if tenantID, ok := tenant.FromContext(ctx); ok {
+		where("tenant_id = $%d", tenantID)
+	}
+	if params.UserID != "" {
+		where("user_id = $%d", params.UserID)
+	}
+	if params.ServiceName != "" {
+		where("service_name = $%d", params.ServiceName)
+	}
+	if params.ServiceNamePrefix != "" {
+		where("service_name LIKE $%d || '%%'", escapeLike(params.ServiceNamePrefix))
+	}
+	if params.PriceMin != nil {
+		where("price >= $%d", *params.PriceMin)
+	}
+	if params.PriceMax != nil {
+		where("price <= $%d", *params.PriceMax)
+	}
+	if params.ActiveAt != nil {
+		where("start_date <= $%d::date", *params.ActiveAt)
+		where("(end_date IS NULL OR end_date >= $%d::date)", *params.ActiveAt)
+	}
+	if params.StartFrom != nil {
+		where("start_date >= $%d::date", *params.StartFrom)
+	}
+	if params.StartTo != nil {
+		where("start_date <= $%d::date", *params.StartTo)
+	}
+	if params.EndFrom != nil {
+		where("end_date >= $%d::date", *params.EndFrom)
+	}
+	if params.EndTo != nil {
+		where("end_date <= $%d::date", *params.EndTo)
+	}
+	whereClause := ""
+	if len(conds) > 0 {
+		whereClause = " WHERE " + strings.Join(conds, " AND ")
+	}
+
+	sortColumn := "id"
+	if models.SubscriptionSortFields[params.Sort] {
+		sortColumn = params.Sort
+	}
+	direction := "ASC"
+	if params.Desc {
+		direction = "DESC"
+	}
+	query := "SELECT " + subscriptionColumns + " FROM " + currentSubscriptions + whereClause +
+		fmt.Sprintf(" ORDER BY %s %s, id %s LIMIT $%d OFFSET $%d", sortColumn, direction, direction, len(args)+1, len(args)+2)
+	var total int
+	subs := []models.Subscription{}
+	err = r.run(ctx, func(q sqlx.ExtContext) error {
+		if err := sqlx.GetContext(ctx, q, &total, "SELECT COUNT(*) FROM "+currentSubscriptions+whereClause, args...); err != nil {
+			return err
+		}
+		return sqlx.SelectContext(ctx, q, &subs, query, append(args, params.Limit, params.Offset)...)
+	})
+	if err != nil {
+		return nil, 0, mapError(ctx, err)
+	}
+	span.SetAttributes(attribute.Int("rows", len(subs)), attribute.Int("total", total))
+	return subs, total, nil
 }
 
-func (r *SubscriptionRepository) List() ([]models.Subscription, error) {
-	query := "SELECT id, service_name, price, user_id, start_date, end_date FROM subscriptions"
-	var subs []models.Subscription
-	err := r.db.Select(&subs, query)
+// CostBreakdown суммирует списания по месяцам и исходным валютам, а при groupBy — ещё и по полю группировки.
+func (r *SubscriptionRepository) CostBreakdown(ctx context.Context, userID, serviceName string, from, to time.Time, groupBy string) (_ []models.CostEntry, err error) {
+	ctx, span := startSpan(ctx, "SubscriptionRepository.CostBreakdown", attribute.String("user_id", userID), attribute.String("service_name", serviceName), attribute.String("group_by", groupBy))
+	defer func() { endSpan(span, err) }()
+	if userID != "" {
+		if err := checkUUID("user_id", userID); err != nil {
+			return nil, err
+		}
+	}
+	// Без группировки group_key — пустая строка: PostgreSQL не допускает константу в GROUP BY
+	// и ORDER BY, поэтому там поле группировки указывается, только если оно задано.
+	groupKey, groupCols := "''", ""
+	if models.CostGroupByFields[groupBy] {
+		groupKey = "c." + groupBy + "::text"
+		groupCols = ", " + groupKey
+	}
+	charges, args := chargesQuery(ctx, userID, serviceName, from, to)
+	query := fmt.Sprintf(`SELECT c.month, c.currency, %[1]s AS group_key, SUM(c.amount) AS cost
+FROM (%[2]s) c
+GROUP BY c.month, c.currency%[3]s
+ORDER BY c.month%[3]s, c.currency`, groupKey, charges, groupCols)
+	entries := []models.CostEntry{}
+	err = r.run(ctx, func(q sqlx.ExtContext) error {
+		return sqlx.SelectContext(ctx, q, &entries, query, args...)
+	})
 	if err != nil {
-		return nil, err
+		return nil, mapError(ctx, err)
 	}
-	return subs, nil
+	span.SetAttributes(attribute.Int("rows", len(entries)))
+	return entries, nil
+}
+
+// chargesQuery строит выборку списаний (month, service_name, user_id, currency, amount) по подпискам
+// за период from..to. Списания идут с периодичностью billing_period начиная со start_date
+// и до конца месяца end_date; бессрочная подписка действует до текущей даты. Сумма списания —
+// последняя цена из subscription_prices, вступившая в силу к дате списания, либо исходная price.
+// Учитываются только подписки арендатора из ctx.
+func chargesQuery(ctx context.Context, userID, serviceName string, from, to time.Time) (string, []interface{}) {
+	query := `SELECT date_trunc('month', ch.charged_at)::date AS month, s.service_name, s.user_id, s.currency,
+	COALESCE((
+		SELECT p.price FROM subscription_prices p
+		WHERE p.subscription_id = s.id AND p.effective_from <= ch.charged_at
+		ORDER BY p.effective_from DESC LIMIT 1
+	), s.price) AS amount
+FROM subscriptions s
+CROSS JOIN LATERAL generate_series(
+	s.start_date::timestamp,
+	LEAST(
+		COALESCE(s.end_date + interval '1 month' - interval '1 day', CURRENT_DATE),
+		$2::date + interval '1 month' - interval '1 day'
+	),
+	CASE s.billing_period
+		WHEN 'weekly' THEN interval '1 week'
+		WHEN 'quarterly' THEN interval '3 months'
+		WHEN 'annual' THEN interval '1 year'
+		ELSE interval '1 month'
+	END
+) AS ch(charged_at)
+WHERE COALESCE(s.end_date, CURRENT_DATE) >= $1::date AND s.start_date <= $2::date
+	AND ch.charged_at >= $1::date`
+	args := []interface{}{from, to}
+	query += tenantCond(ctx, "s.tenant_id", &args)
+	if userID != "" {
+		args = append(args, userID)
+		query += fmt.Sprintf(" AND s.user_id = $%d", len(args))
+	}
+	if serviceName != "" {
+		args = append(args, serviceName)
+		query += fmt.Sprintf(" AND s.service_name = $%d", len(args))
+	}
+	return query, args
+}
+
+// execAffected выполняет изменяющий запрос и возвращает errs.ErrNotFound, если не затронута ни одна строка.
+func (r *SubscriptionRepository) execAffected(ctx context.Context, id, query string, args ...interface{}) error {
+	var res sql.Result
+	err := r.run(ctx, func(q sqlx.ExtContext) error {
+		var err error
+		res, err = q.ExecContext(ctx, query, args...)
+		return err
+	})
+	if err != nil {
+		return mapError(ctx, err)
+	}
+	return checkAffected(res, id)
+}
+
+// tenantCond возвращает условие " AND column = $n" по арендатору из ctx, добавляя его в args.
+// Для внутренних вызовов без арендатора условие пустое.
+func tenantCond(ctx context.Context, column string, args *[]interface{}) string {
+	tenantID, ok := tenant.FromContext(ctx)
+	if !ok {
+		return ""
+	}
+	*args = append(*args, tenantID)
+	return fmt.Sprintf(" AND %s = $%d", column, len(*args))
+}
+
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`).Replace(s)
+}
+
+// checkUUID возвращает errs.ErrInvalidID, если value не является UUID.
+func checkUUID(field, value string) error {
+	if _, err := uuid.Parse(value); err != nil {
+		return fmt.Errorf("%s %q: %w", field, value, errs.ErrInvalidID)
+	}
+	return nil
+}
+
+func notFound(id string) error {
+	return fmt.Errorf("subscription %s: %w", id, errs.ErrNotFound)
+}
+
+func checkAffected(res sql.Result, id string) error {
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return notFound(id)
+	}
+	return nil
+}
+
+// mapError переводит ошибки PostgreSQL в доменные ошибки из пакета errs. Если запрос
+// прерван из-за отмены или истечения ctx, к ошибке добавляется ctx.Err().
+func mapError(ctx context.Context, err error) error {
+	if ctxErr := ctx.Err(); ctxErr != nil && !errors.Is(err, ctxErr) {
+		return fmt.Errorf("%w: %w", ctxErr, err)
+	}
+	var pqErr *pq.Error
+	if !errors.As(err, &pqErr) {
+		return err
+	}
+	switch pqErr.Code.Name() {
+	case "unique_violation", "exclusion_violation":
+		return fmt.Errorf("%w: %w", errs.ErrConflict, err)
+	case "invalid_text_representation":
+		return fmt.Errorf("malformed identifier: %w", errs.ErrInvalidID)
+	}
+	return err
 }
@@ -0,0 +1,569 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"github.com/Tommych123/subscription-service/models"
+	"github.com/Tommych123/subscription-service/service/errs"
+	"github.com/Tommych123/subscription-service/service/tenant"
+	"github.com/golang-migrate/migrate/v4"
+	_ "github.com/golang-migrate/migrate/v4/database/postgres"
+	_ "github.com/golang-migrate/migrate/v4/source/file"
+	"github.com/jmoiron/sqlx"
+	_ "github.com/lib/pq"
+	"os"
+	"slices"
+	"testing"
+	"time"
+)
+
+const (
+	alice   = "11111111-1111-1111-1111-111111111111"
+	bob     = "22222222-2222-2222-2222-222222222222"
+	missing = "33333333-3333-3333-3333-333333333333"
+)
+
+// openTestDB подключается к PostgreSQL из TEST_DATABASE_URL, применяет миграции и очищает
+// таблицы. Без TEST_DATABASE_URL тест пропускается.
+func openTestDB(t *testing.T) *sqlx.DB {
+	t.Helper()
+	dsn := os.Getenv("TEST_DATABASE_URL")
+	if dsn == "" {
+		t.Skip("TEST_DATABASE_URL is not set")
+	}
+	m, err := migrate.New("file://../migrations", dsn)
+	if err != nil {
+		t.Fatalf("init migrations: %v", err)
+	}
+	if err := m.Up(); err != nil && !errors.Is(err, migrate.ErrNoChange) {
+		t.Fatalf("apply migrations: %v", err)
+	}
+	m.Close()
+	db, err := sqlx.Connect("postgres", dsn)
+	if err != nil {
+		t.Fatalf("connect: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+	db.MustExec("TRUNCATE subscriptions, subscription_prices, exchange_rates")
+	return db
+}
+
+// create сохраняет подписку; валюта по умолчанию — рубли.
+func create(t *testing.T, repo *SubscriptionRepository, sub models.Subscription) string {
+	t.Helper()
+	if sub.Currency == "" {
+		sub.Currency = models.DefaultCurrency
+	}
+	id, err := repo.Create(context.Background(), &sub)
+	if err != nil {
+		t.Fatalf("Create(%+v) error: %v", sub, err)
+	}
+	return id
+}
+
+func month(t *testing.T, s string) models.MonthYear {
+	t.Helper()
+	m, err := time.Parse("01-2006", s)
+	if err != nil {
+		t.Fatalf("parse month %q: %v", s, err)
+	}
+	return models.MonthYear{Time: m}
+}
+
+func monthPtr(t *testing.T, s string) *models.MonthYear {
+	m := month(t, s)
+	return &m
+}
+
+// monthCountTotal — прежний расчёт в Go: цена, умноженная на число месяцев подписки внутри периода.
+func monthCountTotal(subs []models.Subscription, userID, serviceName string, from, to time.Time) models.Money {
+	var total models.Money
+	for _, sub := range subs {
+		if (userID != "" && sub.UserID != userID) || (serviceName != "" && sub.ServiceName != serviceName) {
+			continue
+		}
+		start := sub.StartDate.Time
+		end := time.Now()
+		if sub.EndDate != nil {
+			end = sub.EndDate.Time
+		}
+		if end.Before(from) || start.After(to) {
+			continue
+		}
+		if start.Before(from) {
+			start = from
+		}
+		if end.After(to) {
+			end = to
+		}
+		total += sub.Price * models.Money((end.Year()-start.Year())*12+int(end.Month())-int(start.Month())+1)
+	}
+	return total
+}
+
+// totalCost суммирует разбивку без группировки — так итог считает сервис.
+func totalCost(t *testing.T, repo *SubscriptionRepository, userID, serviceName string, from, to time.Time) models.Money {
+	t.Helper()
+	entries, err := repo.CostBreakdown(context.Background(), userID, serviceName, from, to, "")
+	if err != nil {
+		t.Fatalf("CostBreakdown(%q, %q) error: %v", userID, serviceName, err)
+	}
+	var total models.Money
+	for _, e := range entries {
+		total += e.Cost
+	}
+	return total
+}
+
+func TestTotalCostMatchesMonthCount(t *testing.T) {
+	repo := NewSubscriptionRepository(openTestDB(t), false)
+	subs := []models.Subscription{
+		{ServiceName: "Spotify", Price: 400, UserID: alice, StartDate: month(t, "07-2024"), EndDate: monthPtr(t, "03-2025")},
+		{ServiceName: "Netflix", Price: 799, UserID: alice, StartDate: month(t, "01-2024")},
+		{ServiceName: "Yandex Plus", Price: 299, UserID: bob, StartDate: month(t, "02-2025"), EndDate: monthPtr(t, "02-2025")},
+		{ServiceName: "iCloud", Price: 149, UserID: bob, StartDate: month(t, "11-2024"), EndDate: monthPtr(t, "06-2025")},
+		{ServiceName: "Spotify", Price: 199, UserID: bob, StartDate: month(t, "05-2023"), EndDate: monthPtr(t, "08-2023")},
+	}
+	for _, sub := range subs {
+		create(t, repo, sub)
+	}
+	tests := []struct {
+		from, to    string
+		userID      string
+		serviceName string
+	}{
+		{from: "01-2024", to: "12-2024"},
+		{from: "01-2025", to: "12-2025"},
+		{from: "07-2024", to: "07-2024"},
+		{from: "03-2025", to: "03-2025"},
+		{from: "01-2023", to: "06-2025"},
+		{from: "09-2023", to: "12-2023"},
+		{from: "01-2023", to: "12-2025", userID: alice},
+		{from: "01-2023", to: "12-2025", serviceName: "Spotify"},
+		{from: "01-2023", to: "12-2025", userID: bob, serviceName: "Spotify"},
+	}
+	for _, tt := range tests {
+		from, to := month(t, tt.from).Time, month(t, tt.to).Time
+		got := totalCost(t, repo, tt.userID, tt.serviceName, from, to)
+		if want := monthCountTotal(subs, tt.userID, tt.serviceName, from, to); got != want {
+			t.Errorf("total cost (%+v) = %d, want %d", tt, got, want)
+		}
+	}
+}
+
+// costRow — запись разбивки в виде, удобном для сравнения.
+type costRow struct {
+	month string
+	group string
+	cost  models.Money
+}
+
+func costRows(entries []models.CostEntry) []costRow {
+	rows := []costRow{}
+	for _, e := range entries {
+		rows = append(rows, costRow{month: e.Month.Format("01-2006"), group: e.Group, cost: e.Cost})
+	}
+	return rows
+}
+
+func TestCostBreakdown(t *testing.T) {
+	ctx := context.Background()
+	repo := NewSubscriptionRepository(openTestDB(t), false)
+	for _, sub := range []models.Subscription{
+		{ServiceName: "Spotify", Price: 400, UserID: alice, StartDate: month(t, "01-2025"), EndDate: monthPtr(t, "03-2025")},
+		{ServiceName: "Netflix", Price: 800, UserID: alice, StartDate: month(t, "02-2025"), EndDate: monthPtr(t, "02-2025")},
+		{ServiceName: "Spotify", Price: 300, UserID: bob, StartDate: month(t, "03-2025"), EndDate: monthPtr(t, "06-2025")},
+	} {
+		create(t, repo, sub)
+	}
+	from, to := month(t, "01-2025").Time, month(t, "04-2025").Time
+	tests := []struct {
+		name        string
+		userID      string
+		serviceName string
+		groupBy     string
+		want        []costRow
+	}{
+		{
+			name: "ungrouped",
+			want: []costRow{{"01-2025", "", 400}, {"02-2025", "", 1200}, {"03-2025", "", 700}, {"04-2025", "", 300}},
+		},
+		{
+			name:    "by service_name",
+			groupBy: "service_name",
+			want: []costRow{
+				{"01-2025", "Spotify", 400},
+				{"02-2025", "Netflix", 800}, {"02-2025", "Spotify", 400},
+				{"03-2025", "Spotify", 700},
+				{"04-2025", "Spotify", 300},
+			},
+		},
+		{
+			name:    "by user_id",
+			groupBy: "user_id",
+			want: []costRow{
+				{"01-2025", alice, 400},
+				{"02-2025", alice, 1200},
+				{"03-2025", alice, 400}, {"03-2025", bob, 300},
+				{"04-2025", bob, 300},
+			},
+		},
+		{
+			name:        "filtered and grouped",
+			userID:      alice,
+			serviceName: "Spotify",
+			groupBy:     "service_name",
+			want:        []costRow{{"01-2025", "Spotify", 400}, {"02-2025", "Spotify", 400}, {"03-2025", "Spotify", 400}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			entries, err := repo.CostBreakdown(ctx, tt.userID, tt.serviceName, from, to, tt.groupBy)
+			if err != nil {
+				t.Fatalf("CostBreakdown() error: %v", err)
+			}
+			if got := costRows(entries); !slices.Equal(got, tt.want) {
+				t.Errorf("CostBreakdown() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+	if total := totalCost(t, repo, "", "", from, to); total != 2600 {
+		t.Errorf("total cost = %d, want 2600", total)
+	}
+}
+
+func TestCostBreakdownBillingPeriods(t *testing.T) {
+	ctx := context.Background()
+	repo := NewSubscriptionRepository(openTestDB(t), false)
+	for _, sub := range []models.Subscription{
+		{ServiceName: "weekly", Price: 100, BillingPeriod: models.BillingWeekly},
+		{ServiceName: "monthly", Price: 1000, BillingPeriod: models.BillingMonthly},
+		{ServiceName: "quarterly", Price: 3000, BillingPeriod: models.BillingQuarterly},
+		{ServiceName: "annual", Price: 12000, BillingPeriod: models.BillingAnnual},
+	} {
+		sub.UserID = alice
+		sub.StartDate = month(t, "01-2025")
+		sub.EndDate = monthPtr(t, "06-2025")
+		create(t, repo, sub)
+	}
+	tests := []struct {
+		serviceName string
+		from, to    string
+		want        []costRow
+	}{
+		{
+			serviceName: "weekly", from: "01-2025", to: "06-2025",
+			want: []costRow{{"01-2025", "", 500}, {"02-2025", "", 400}, {"03-2025", "", 400}, {"04-2025", "", 500}, {"05-2025", "", 400}, {"06-2025", "", 400}},
+		},
+		{
+			serviceName: "monthly", from: "03-2025", to: "08-2025",
+			want: []costRow{{"03-2025", "", 1000}, {"04-2025", "", 1000}, {"05-2025", "", 1000}, {"06-2025", "", 1000}},
+		},
+		{
+			serviceName: "quarterly", from: "01-2025", to: "06-2025",
+			want: []costRow{{"01-2025", "", 3000}, {"04-2025", "", 3000}},
+		},
+		// Списания отсчитываются от start_date, а не от начала запрошенного периода.
+		{
+			serviceName: "quarterly", from: "02-2025", to: "05-2025",
+			want: []costRow{{"04-2025", "", 3000}},
+		},
+		{
+			serviceName: "annual", from: "01-2025", to: "06-2025",
+			want: []costRow{{"01-2025", "", 12000}},
+		},
+		{
+			serviceName: "annual", from: "02-2025", to: "06-2025",
+			want: []costRow{},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.serviceName+" "+tt.from+".."+tt.to, func(t *testing.T) {
+			entries, err := repo.CostBreakdown(ctx, "", tt.serviceName, month(t, tt.from).Time, month(t, tt.to).Time, "")
+			if err != nil {
+				t.Fatalf("CostBreakdown() error: %v", err)
+			}
+			if got := costRows(entries); !slices.Equal(got, tt.want) {
+				t.Errorf("CostBreakdown() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCostBreakdownCurrencies(t *testing.T) {
+	ctx := context.Background()
+	repo := NewSubscriptionRepository(openTestDB(t), false)
+	for _, sub := range []models.Subscription{
+		{ServiceName: "Spotify", Price: 400, UserID: alice, StartDate: month(t, "01-2025"), EndDate: monthPtr(t, "02-2025")},
+		{ServiceName: "Netflix", Price: 10, Currency: "USD", UserID: alice, StartDate: month(t, "02-2025"), EndDate: monthPtr(t, "02-2025")},
+		{ServiceName: "YouTube", Price: 5, Currency: "USD", UserID: bob, StartDate: month(t, "02-2025"), EndDate: monthPtr(t, "02-2025")},
+	} {
+		create(t, repo, sub)
+	}
+	entries, err := repo.CostBreakdown(ctx, "", "", month(t, "01-2025").Time, month(t, "02-2025").Time, "")
+	if err != nil {
+		t.Fatalf("CostBreakdown() error: %v", err)
+	}
+	type row struct {
+		month    string
+		currency models.Currency
+		cost     models.Money
+	}
+	got := []row{}
+	for _, e := range entries {
+		got = append(got, row{e.Month.Format("01-2006"), e.Currency, e.Cost})
+	}
+	want := []row{{"01-2025", "RUB", 400}, {"02-2025", "RUB", 400}, {"02-2025", "USD", 15}}
+	if !slices.Equal(got, want) {
+		t.Errorf("CostBreakdown() = %v, want %v", got, want)
+	}
+}
+
+func TestPriceHistory(t *testing.T) {
+	ctx := context.Background()
+	repo := NewSubscriptionRepository(openTestDB(t), false)
+	id := create(t, repo, models.Subscription{ServiceName: "Spotify", Price: 10000, UserID: alice, StartDate: month(t, "01-2025")})
+	nextYear := models.MonthYear{Time: time.Date(time.Now().Year()+1, time.January, 1, 0, 0, 0, 0, time.UTC)}
+	for _, change := range []models.PriceChange{
+		{Price: 20000, EffectiveFrom: month(t, "03-2025")},
+		{Price: 30000, EffectiveFrom: nextYear},
+	} {
+		if err := repo.SchedulePrice(ctx, id, change); err != nil {
+			t.Fatalf("SchedulePrice(%+v) error: %v", change, err)
+		}
+	}
+	if err := repo.SchedulePrice(ctx, missing, models.PriceChange{Price: 1, EffectiveFrom: nextYear}); !errors.Is(err, errs.ErrNotFound) {
+		t.Errorf("SchedulePrice(missing) error = %v, want ErrNotFound", err)
+	}
+
+	entries, err := repo.CostBreakdown(ctx, "", "", month(t, "01-2025").Time, month(t, "04-2025").Time, "")
+	if err != nil {
+		t.Fatalf("CostBreakdown() error: %v", err)
+	}
+	want := []costRow{{"01-2025", "", 10000}, {"02-2025", "", 10000}, {"03-2025", "", 20000}, {"04-2025", "", 20000}}
+	if got := costRows(entries); !slices.Equal(got, want) {
+		t.Errorf("CostBreakdown() = %v, want %v", got, want)
+	}
+
+	// Текущая цена — последняя вступившая в силу, будущее изменение на неё не влияет.
+	sub, err := repo.GetByID(ctx, id)
+	if err != nil {
+		t.Fatalf("GetByID() error: %v", err)
+	}
+	if sub.Price != 20000 {
+		t.Errorf("GetByID().Price = %s, want 200.00", sub.Price)
+	}
+	priceMin := models.Money(15000)
+	subs, total, err := repo.List(ctx, models.ListParams{PriceMin: &priceMin, Limit: 10})
+	if err != nil {
+		t.Fatalf("List() error: %v", err)
+	}
+	if total != 1 || len(subs) != 1 || subs[0].Price != 20000 {
+		t.Errorf("List(price_min=150) = %v, %d, want the subscription with price 200.00", subs, total)
+	}
+
+	// Update не трогает исходную цену: история остаётся прежней.
+	sub.ServiceName = "Spotify Family"
+	if err := repo.Update(ctx, sub); err != nil {
+		t.Fatalf("Update() error: %v", err)
+	}
+	var base models.Money
+	if err := repo.db.GetContext(ctx, &base, "SELECT price FROM subscriptions WHERE id = $1", id); err != nil {
+		t.Fatalf("select base price: %v", err)
+	}
+	if base != 10000 {
+		t.Errorf("base price after Update = %s, want 100.00", base)
+	}
+}
+
+func TestPatch(t *testing.T) {
+	ctx := context.Background()
+	repo := NewSubscriptionRepository(openTestDB(t), false)
+	id := create(t, repo, models.Subscription{ServiceName: "Spotify", Price: 10000, UserID: alice, StartDate: month(t, "01-2025"), EndDate: monthPtr(t, "06-2025")})
+	if err := repo.SchedulePrice(ctx, id, models.PriceChange{Price: 20000, EffectiveFrom: month(t, "03-2025")}); err != nil {
+		t.Fatalf("SchedulePrice() error: %v", err)
+	}
+	allow := func(current, next *models.Subscription) error { return nil }
+
+	name := "Spotify Family"
+	sub, err := repo.Patch(ctx, id, &models.SubscriptionPatch{ServiceName: &name, EndDateSet: true}, allow)
+	if err != nil {
+		t.Fatalf("Patch() error: %v", err)
+	}
+	if sub.ServiceName != name || sub.EndDate != nil || sub.Price != 20000 {
+		t.Errorf("Patch() = %+v, want renamed open-ended subscription with current price 200.00", sub)
+	}
+	if got, err := repo.GetByID(ctx, id); err != nil || got.ServiceName != name || got.EndDate != nil {
+		t.Errorf("GetByID() after Patch = %+v, %v", got, err)
+	}
+
+	// Ошибка check откатывает изменения и возвращается как есть.
+	errRejected := errors.New("rejected")
+	var seen [2]models.Money
+	price := models.Money(30000)
+	_, err = repo.Patch(ctx, id, &models.SubscriptionPatch{Price: &price, ServiceName: &name}, func(current, next *models.Subscription) error {
+		seen = [2]models.Money{current.Price, next.Price}
+		return errRejected
+	})
+	if !errors.Is(err, errRejected) {
+		t.Fatalf("Patch() error = %v, want check error", err)
+	}
+	if seen != [2]models.Money{20000, 30000} {
+		t.Errorf("check got prices %v, want current 200.00 and patched 300.00", seen)
+	}
+
+	if _, err := repo.Patch(ctx, missing, &models.SubscriptionPatch{ServiceName: &name}, allow); !errors.Is(err, errs.ErrNotFound) {
+		t.Errorf("Patch(missing) error = %v, want ErrNotFound", err)
+	}
+}
+
+func TestDomainErrors(t *testing.T) {
+	ctx := context.Background()
+	repo := NewSubscriptionRepository(openTestDB(t), false)
+	tests := []struct {
+		name string
+		err  error
+		want error
+	}{
+		{name: "get missing", err: func() error { _, err := repo.GetByID(ctx, missing); return err }(), want: errs.ErrNotFound},
+		{name: "get invalid id", err: func() error { _, err := repo.GetByID(ctx, "42"); return err }(), want: errs.ErrInvalidID},
+		{name: "update missing", err: repo.Update(ctx, &models.Subscription{ID: missing, ServiceName: "x", UserID: alice, Currency: models.DefaultCurrency, StartDate: month(t, "01-2025")}), want: errs.ErrNotFound},
+		{name: "delete missing", err: repo.Delete(ctx, missing), want: errs.ErrNotFound},
+		{name: "create invalid user", err: func() error { _, err := repo.Create(ctx, &models.Subscription{UserID: "bob"}); return err }(), want: errs.ErrInvalidID},
+		{name: "list invalid user", err: func() error { _, _, err := repo.List(ctx, models.ListParams{UserID: "bob", Limit: 1}); return err }(), want: errs.ErrInvalidID},
+	}
+	for _, tt := range tests {
+		if !errors.Is(tt.err, tt.want) {
+			t.Errorf("%s: error = %v, want %v", tt.name, tt.err, tt.want)
+		}
+	}
+}
+
+func TestTenantIsolation(t *testing.T) {
+	repo := NewSubscriptionRepository(openTestDB(t), false)
+	acme, globex := tenant.WithTenant(context.Background(), "acme"), tenant.WithTenant(context.Background(), "globex")
+	id, err := repo.Create(acme, &models.Subscription{ServiceName: "Spotify", Price: 10000, Currency: models.DefaultCurrency, UserID: alice, StartDate: month(t, "01-2025"), BillingPeriod: models.BillingMonthly})
+	if err != nil {
+		t.Fatalf("Create() error: %v", err)
+	}
+
+	if _, err := repo.GetByID(globex, id); !errors.Is(err, errs.ErrNotFound) {
+		t.Errorf("GetByID(other tenant) error = %v, want ErrNotFound", err)
+	}
+	name := "Stolen"
+	if _, err := repo.Patch(globex, id, &models.SubscriptionPatch{ServiceName: &name}, func(_, _ *models.Subscription) error { return nil }); !errors.Is(err, errs.ErrNotFound) {
+		t.Errorf("Patch(other tenant) error = %v, want ErrNotFound", err)
+	}
+	if err := repo.SchedulePrice(globex, id, models.PriceChange{Price: 1, EffectiveFrom: month(t, "02-2025")}); !errors.Is(err, errs.ErrNotFound) {
+		t.Errorf("SchedulePrice(other tenant) error = %v, want ErrNotFound", err)
+	}
+	if err := repo.Delete(globex, id); !errors.Is(err, errs.ErrNotFound) {
+		t.Errorf("Delete(other tenant) error = %v, want ErrNotFound", err)
+	}
+	if _, total, err := repo.List(globex, models.ListParams{Limit: 10}); err != nil || total != 0 {
+		t.Errorf("List(other tenant) total = %d, %v, want 0", total, err)
+	}
+	if cost := totalCost(t, repo, "", "", month(t, "01-2025").Time, month(t, "03-2025").Time); cost != 30000 {
+		t.Errorf("total without tenant = %s, want 300.00", cost)
+	}
+	entries, err := repo.CostBreakdown(globex, "", "", month(t, "01-2025").Time, month(t, "03-2025").Time, "")
+	if err != nil || len(entries) != 0 {
+		t.Errorf("CostBreakdown(other tenant) = %+v, %v, want none", entries, err)
+	}
+	if sub, err := repo.GetByID(acme, id); err != nil || sub.ServiceName != "Spotify" {
+		t.Errorf("GetByID(own tenant) = %+v, %v, want unchanged subscription", sub, err)
+	}
+}
+
+// TestTenantRLS проверяет политики миграции 008 без условий по арендатору в запросах. Запросы
+// выполняются под ролью без прав владельца и суперпользователя, иначе политики не действуют.
+func TestTenantRLS(t *testing.T) {
+	db := openTestDB(t)
+	repo := NewSubscriptionRepository(db, false)
+	acme := tenant.WithTenant(context.Background(), "acme")
+	acmeID := create(t, repo, models.Subscription{ServiceName: "Spotify", Price: 10000, UserID: alice, StartDate: month(t, "01-2025")})
+	if _, err := repo.Create(acme, &models.Subscription{ServiceName: "Netflix", Price: 20000, Currency: models.DefaultCurrency, UserID: alice, StartDate: month(t, "01-2025"), BillingPeriod: models.BillingMonthly}); err != nil {
+		t.Fatalf("Create(acme) error: %v", err)
+	}
+	if err := repo.SchedulePrice(context.Background(), acmeID, models.PriceChange{Price: 15000, EffectiveFrom: month(t, "03-2025")}); err != nil {
+		t.Fatalf("SchedulePrice() error: %v", err)
+	}
+
+	db.MustExec(`DO $$ BEGIN
+	IF NOT EXISTS (SELECT FROM pg_roles WHERE rolname = 'subscriptions_rls_test') THEN
+		CREATE ROLE subscriptions_rls_test NOSUPERUSER NOBYPASSRLS;
+	END IF;
+END $$`)
+	db.MustExec("GRANT SELECT, INSERT, UPDATE, DELETE ON subscriptions, subscription_prices TO subscriptions_rls_test")
+	db.MustExec("ALTER TABLE subscriptions ENABLE ROW LEVEL SECURITY")
+	db.MustExec("ALTER TABLE subscription_prices ENABLE ROW LEVEL SECURITY")
+	t.Cleanup(func() {
+		db.MustExec("ALTER TABLE subscriptions DISABLE ROW LEVEL SECURITY")
+		db.MustExec("ALTER TABLE subscription_prices DISABLE ROW LEVEL SECURITY")
+	})
+
+	// asTenant выполняет fn в транзакции под ролью subscriptions_rls_test с заданными переменными сеанса.
+	asTenant := func(settings map[string]string, fn func(tx *sqlx.Tx) error) error {
+		tx, err := db.Beginx()
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer tx.Rollback()
+		tx.MustExec("SET LOCAL ROLE subscriptions_rls_test")
+		for name, value := range settings {
+			tx.MustExec("SELECT set_config($1, $2, true)", name, value)
+		}
+		return fn(tx)
+	}
+	count := func(settings map[string]string, query string) int {
+		t.Helper()
+		var n int
+		if err := asTenant(settings, func(tx *sqlx.Tx) error { return tx.Get(&n, query) }); err != nil {
+			t.Fatalf("%s: %v", query, err)
+		}
+		return n
+	}
+
+	defaultTenant := map[string]string{"app.tenant_id": tenant.Default}
+	tests := []struct {
+		name     string
+		settings map[string]string
+		query    string
+		want     int
+	}{
+		{name: "own tenant", settings: defaultTenant, query: "SELECT COUNT(*) FROM subscriptions", want: 1},
+		{name: "other tenant is hidden", settings: defaultTenant, query: "SELECT COUNT(*) FROM subscriptions WHERE tenant_id = 'acme'", want: 0},
+		{name: "own prices", settings: defaultTenant, query: "SELECT COUNT(*) FROM subscription_prices", want: 1},
+		{name: "other tenant prices are hidden", settings: map[string]string{"app.tenant_id": "acme"}, query: "SELECT COUNT(*) FROM subscription_prices", want: 0},
+		{name: "no tenant sees nothing", query: "SELECT COUNT(*) FROM subscriptions", want: 0},
+		{name: "all tenants", settings: map[string]string{"app.all_tenants": "on"}, query: "SELECT COUNT(*) FROM subscriptions", want: 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := count(tt.settings, tt.query); got != tt.want {
+				t.Errorf("count = %d, want %d", got, tt.want)
+			}
+		})
+	}
+
+	err := asTenant(defaultTenant, func(tx *sqlx.Tx) error {
+		_, err := tx.Exec(`INSERT INTO subscriptions (id, tenant_id, service_name, price, currency, user_id, start_date, billing_period)
+VALUES ('44444444-4444-4444-4444-444444444444', 'acme', 'Smuggled', 100, 'RUB', $1, '2025-01-01', 'monthly')`, alice)
+		return err
+	})
+	if err == nil {
+		t.Error("insert into another tenant: want row-level security violation")
+	}
+	var updated int64
+	err = asTenant(defaultTenant, func(tx *sqlx.Tx) error {
+		res, err := tx.Exec("UPDATE subscriptions SET service_name = 'Stolen' WHERE tenant_id = 'acme'")
+		if err != nil {
+			return err
+		}
+		updated, err = res.RowsAffected()
+		return err
+	})
+	if err != nil || updated != 0 {
+		t.Errorf("update of another tenant: %d rows, %v, want 0 rows", updated, err)
+	}
+}
@@ -0,0 +1,42 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"github.com/Tommych123/subscription-service/models"
+	"github.com/Tommych123/subscription-service/service/auth"
+	"github.com/Tommych123/subscription-service/service/errs"
+)
+
+// authorizeUser проверяет, что вызывающий может работать с данными пользователя userID:
+// обычный пользователь — только со своими, пользователь с правом auth.PermAllUsers,
+// сервисный клиент и вызовы без аутентификации — с любыми.
+func authorizeUser(ctx context.Context, userID string) error {
+	p, ok := auth.FromContext(ctx)
+	if !ok || p.AllUsers() || userID == p.UserID {
+		return nil
+	}
+	return fmt.Errorf("%w: user %s cannot access subscriptions of user %s", errs.ErrForbidden, p.UserID, userID)
+}
+
+// scopeUserID возвращает фильтр по пользователю для выборок и итогов. Если фильтр не задан,
+// обычный пользователь получает только свои данные.
+func scopeUserID(ctx context.Context, userID string) (string, error) {
+	if p, ok := auth.FromContext(ctx); ok && !p.AllUsers() && userID == "" {
+		return p.UserID, nil
+	}
+	return userID, authorizeUser(ctx, userID)
+}
+
+// getOwned возвращает подписку, если она принадлежит вызывающему. Чужая подписка для
+// обычного пользователя выглядит как несуществующая.
+func (s *SubscriptionService) getOwned(ctx context.Context, id string) (*models.Subscription, error) {
+	sub, err := s.repo.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if authorizeUser(ctx, sub.UserID) != nil {
+		return nil, fmt.Errorf("subscription %s: %w", id, errs.ErrNotFound)
+	}
+	return sub, nil
+}
@@ -0,0 +1,126 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"github.com/Tommych123/subscription-service/internal/logger"
+	"github.com/Tommych123/subscription-service/models"
+	"github.com/Tommych123/subscription-service/service/errs"
+	"github.com/Tommych123/subscription-service/service/tenant"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+	"strings"
+	"time"
+)
+
+// apiKeyPrefix отличает API-ключи сервиса от других секретов; keyPrefixLength — сколько
+// первых символов ключа сохраняется открыто для поиска ключа в списке.
+const (
+	apiKeyPrefix    = "sk_"
+	keyPrefixLength = len(apiKeyPrefix) + 6
+)
+
+// touchInterval — как часто обновляется время последнего использования ключа: ключ
+// проверяется на каждом запросе, а запись в базу нужна не чаще раза в минуту.
+const touchInterval = time.Minute
+
+// APIKeyStore — хранилище API-ключей. Реализации: repository.APIKeyRepository (PostgreSQL)
+// и repository.MemoryAPIKeyRepository (память процесса).
+type APIKeyStore interface {
+	Create(ctx context.Context, key *models.APIKey, hash string) error
+	GetByHash(ctx context.Context, hash string) (*models.APIKey, error)
+	List(ctx context.Context) ([]models.APIKey, error)
+	Revoke(ctx context.Context, id string, at time.Time) error
+	Touch(ctx context.Context, id string, at time.Time) error
+}
+
+// APIKeyService выпускает, отзывает и проверяет API-ключи сервисных клиентов.
+type APIKeyService struct {
+	repo   APIKeyStore
+	logger *zap.Logger
+	now    func() time.Time
+}
+
+func NewAPIKeyService(repo APIKeyStore, logger *zap.Logger) *APIKeyService {
+	return &APIKeyService{repo: repo, logger: logger, now: time.Now}
+}
+
+// Mint выпускает новый ключ для арендатора из ctx. Открытое значение ключа возвращается только здесь.
+func (s *APIKeyService) Mint(ctx context.Context, req models.NewAPIKey) (*models.IssuedAPIKey, error) {
+	if err := validateNewAPIKey(req, s.now()); err != nil {
+		return nil, err
+	}
+	tenantID, ok := tenant.FromContext(ctx)
+	if !ok {
+		tenantID = tenant.Default
+	}
+	raw := make([]byte, 32)
+	if _, err := rand.Read(raw); err != nil {
+		return nil, err
+	}
+	secret := apiKeyPrefix + base64.RawURLEncoding.EncodeToString(raw)
+	key := models.APIKey{
+		ID:        uuid.New().String(),
+		TenantID:  tenantID,
+		Name:      req.Name,
+		Prefix:    secret[:keyPrefixLength],
+		Scopes:    req.Scopes,
+		CreatedAt: s.now().UTC(),
+		ExpiresAt: req.ExpiresAt,
+	}
+	if err := s.repo.Create(ctx, &key, hashAPIKey(secret)); err != nil {
+		return nil, err
+	}
+	logger.FromContext(ctx, s.logger).Info("API key minted", zap.String("id", key.ID), zap.String("name", key.Name), zap.Strings("scopes", key.Scopes))
+	return &models.IssuedAPIKey{APIKey: key, Key: secret}, nil
+}
+
+func (s *APIKeyService) List(ctx context.Context) ([]models.APIKey, error) {
+	return s.repo.List(ctx)
+}
+
+func (s *APIKeyService) Revoke(ctx context.Context, id string) error {
+	if err := s.repo.Revoke(ctx, id, s.now().UTC()); err != nil {
+		return err
+	}
+	logger.FromContext(ctx, s.logger).Info("API key revoked", zap.String("id", id))
+	return nil
+}
+
+// Authenticate находит действующий ключ по его открытому значению и отмечает время использования,
+// если с прошлой отметки прошло не меньше touchInterval.
+// Неизвестный, отозванный или просроченный ключ даёт errs.ErrUnauthorized.
+func (s *APIKeyService) Authenticate(ctx context.Context, secret string) (*models.APIKey, error) {
+	if !strings.HasPrefix(secret, apiKeyPrefix) {
+		return nil, fmt.Errorf("%w: malformed api key", errs.ErrUnauthorized)
+	}
+	key, err := s.repo.GetByHash(ctx, hashAPIKey(secret))
+	if errors.Is(err, errs.ErrNotFound) {
+		return nil, fmt.Errorf("%w: unknown api key", errs.ErrUnauthorized)
+	}
+	if err != nil {
+		return nil, err
+	}
+	now := s.now().UTC()
+	if !key.Active(now) {
+		return nil, fmt.Errorf("%w: api key %s is revoked or expired", errs.ErrUnauthorized, key.ID)
+	}
+	if key.LastUsedAt != nil && now.Sub(*key.LastUsedAt) < touchInterval {
+		return key, nil
+	}
+	if err := s.repo.Touch(ctx, key.ID, now); err != nil {
+		logger.FromContext(ctx, s.logger).Warn("Failed to update API key last use", zap.Error(err), zap.String("id", key.ID))
+	}
+	return key, nil
+}
+
+// hashAPIKey возвращает SHA-256 ключа: ключи случайные и длинные, поэтому медленный хеш не нужен.
+func hashAPIKey(secret string) string {
+	sum := sha256.Sum256([]byte(secret))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,146 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"github.com/Tommych123/subscription-service/models"
+	"github.com/Tommych123/subscription-service/repository"
+	"github.com/Tommych123/subscription-service/service/errs"
+	"go.uber.org/zap"
+	"slices"
+	"testing"
+	"time"
+)
+
+// countingKeyStore считает вызовы Touch, чтобы проверить, что время использования пишется не на каждый запрос.
+type countingKeyStore struct {
+	*repository.MemoryAPIKeyRepository
+	touches int
+}
+
+func (r *countingKeyStore) Touch(ctx context.Context, id string, at time.Time) error {
+	r.touches++
+	return r.MemoryAPIKeyRepository.Touch(ctx, id, at)
+}
+
+func newTestAPIKeyService(t *testing.T) (*APIKeyService, *countingKeyStore, *time.Time) {
+	t.Helper()
+	store := &countingKeyStore{MemoryAPIKeyRepository: repository.NewMemoryAPIKeyRepository()}
+	now := time.Date(2025, 1, 15, 12, 0, 0, 0, time.UTC)
+	s := NewAPIKeyService(store, zap.NewNop())
+	s.now = func() time.Time { return now }
+	return s, store, &now
+}
+
+func mint(t *testing.T, s *APIKeyService, req models.NewAPIKey) *models.IssuedAPIKey {
+	t.Helper()
+	issued, err := s.Mint(context.Background(), req)
+	if err != nil {
+		t.Fatalf("Mint(%+v) error: %v", req, err)
+	}
+	return issued
+}
+
+func TestMintValidation(t *testing.T) {
+	s, _, now := newTestAPIKeyService(t)
+	past := now.Add(-time.Hour)
+	tests := []struct {
+		name       string
+		req        models.NewAPIKey
+		wantFields []string
+	}{
+		{name: "valid", req: models.NewAPIKey{Name: "billing-cron", Scopes: []string{models.ScopeSubscriptionsRead, models.ScopeTotalsRead}}},
+		{name: "empty name", req: models.NewAPIKey{Name: " ", Scopes: []string{models.ScopeTotalsRead}}, wantFields: []string{"name"}},
+		{name: "no scopes", req: models.NewAPIKey{Name: "billing-cron"}, wantFields: []string{"scopes"}},
+		{name: "unknown scope", req: models.NewAPIKey{Name: "billing-cron", Scopes: []string{models.ScopeTotalsRead, "admin"}}, wantFields: []string{"scopes"}},
+		{name: "expired", req: models.NewAPIKey{Name: "billing-cron", Scopes: []string{models.ScopeTotalsRead}, ExpiresAt: &past}, wantFields: []string{"expires_at"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			issued, err := s.Mint(context.Background(), tt.req)
+			if got := fieldErrors(t, err); !slices.Equal(got, tt.wantFields) {
+				t.Fatalf("Mint() fields = %v, want %v", got, tt.wantFields)
+			}
+			if err != nil {
+				return
+			}
+			if issued.Prefix != issued.Key[:keyPrefixLength] || !slices.Equal(issued.Scopes, tt.req.Scopes) {
+				t.Errorf("Mint() = %+v, want prefix of the key and requested scopes", issued)
+			}
+		})
+	}
+}
+
+func TestAPIKeyAuthenticate(t *testing.T) {
+	s, _, now := newTestAPIKeyService(t)
+	ctx := context.Background()
+	expiresAt := now.Add(time.Hour)
+	valid := mint(t, s, models.NewAPIKey{Name: "billing-cron", Scopes: []string{models.ScopeTotalsRead}})
+	expiring := mint(t, s, models.NewAPIKey{Name: "report", Scopes: []string{models.ScopeTotalsRead}, ExpiresAt: &expiresAt})
+	revoked := mint(t, s, models.NewAPIKey{Name: "old", Scopes: []string{models.ScopeTotalsRead}})
+	if err := s.Revoke(ctx, revoked.ID); err != nil {
+		t.Fatalf("Revoke() error: %v", err)
+	}
+	if err := s.Revoke(ctx, revoked.ID); !errors.Is(err, errs.ErrNotFound) {
+		t.Errorf("Revoke(revoked) error = %v, want ErrNotFound", err)
+	}
+
+	tests := []struct {
+		name    string
+		secret  string
+		wantErr bool
+	}{
+		{name: "valid", secret: valid.Key},
+		{name: "not yet expired", secret: expiring.Key},
+		{name: "malformed", secret: "not-a-key", wantErr: true},
+		{name: "unknown", secret: apiKeyPrefix + "unknown", wantErr: true},
+		{name: "revoked", secret: revoked.Key, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			key, err := s.Authenticate(ctx, tt.secret)
+			if tt.wantErr {
+				if !errors.Is(err, errs.ErrUnauthorized) {
+					t.Errorf("Authenticate() = %+v, %v, want ErrUnauthorized", key, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Authenticate() error: %v", err)
+			}
+		})
+	}
+
+	*now = expiresAt
+	if _, err := s.Authenticate(ctx, expiring.Key); !errors.Is(err, errs.ErrUnauthorized) {
+		t.Errorf("Authenticate(expired) error = %v, want ErrUnauthorized", err)
+	}
+}
+
+func TestAPIKeyTouchThrottle(t *testing.T) {
+	s, store, now := newTestAPIKeyService(t)
+	ctx := context.Background()
+	issued := mint(t, s, models.NewAPIKey{Name: "billing-cron", Scopes: []string{models.ScopeTotalsRead}})
+	first := *now
+
+	for range 3 {
+		if _, err := s.Authenticate(ctx, issued.Key); err != nil {
+			t.Fatalf("Authenticate() error: %v", err)
+		}
+		*now = now.Add(10 * time.Second)
+	}
+	if store.touches != 1 {
+		t.Errorf("Touch called %d times within %s, want 1", store.touches, touchInterval)
+	}
+	*now = first.Add(touchInterval)
+	key, err := s.Authenticate(ctx, issued.Key)
+	if err != nil {
+		t.Fatalf("Authenticate() error: %v", err)
+	}
+	if store.touches != 2 {
+		t.Errorf("Touch called %d times after %s, want 2", store.touches, touchInterval)
+	}
+	if key.LastUsedAt == nil || !key.LastUsedAt.Equal(first) {
+		t.Errorf("LastUsedAt = %v, want previous use %v", key.LastUsedAt, first)
+	}
+}
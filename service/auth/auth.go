@@ -0,0 +1,55 @@
+// Package auth описывает вызывающую сторону запроса (Principal), её права и проверку JWT,
+// из которых она берётся.
+package auth
+
+import (
+	"context"
+	"slices"
+)
+
+// Principal — аутентифицированный пользователь (по JWT) или сервисный клиент (по API-ключу),
+// от имени которого выполняется запрос. У сервисного клиента заполнен APIKeyID, а Permissions —
+// области доступа ключа; у пользователя Permissions выводятся из ролей по Policy.
+// TenantID — арендатор, к которому привязан ключ или токен; пустой, если токен его не задаёт.
+type Principal struct {
+	UserID      string
+	Roles       []string
+	APIKeyID    string
+	Permissions []string
+	TenantID    string
+}
+
+// HasRole сообщает, есть ли у пользователя роль role.
+func (p *Principal) HasRole(role string) bool {
+	return slices.Contains(p.Roles, role)
+}
+
+// IsService сообщает, что запрос выполняется по API-ключу.
+func (p *Principal) IsService() bool {
+	return p.APIKeyID != ""
+}
+
+// Can сообщает, есть ли у вызывающего право perm.
+func (p *Principal) Can(perm string) bool {
+	return slices.Contains(p.Permissions, perm)
+}
+
+// AllUsers сообщает, может ли вызывающий работать с данными всех пользователей: это
+// пользователи с правом PermAllUsers и сервисные клиенты, чьи права ограничены областями доступа ключа.
+func (p *Principal) AllUsers() bool {
+	return p.Can(PermAllUsers) || p.IsService()
+}
+
+type ctxKey struct{}
+
+// WithPrincipal возвращает копию ctx с пользователем p.
+func WithPrincipal(ctx context.Context, p *Principal) context.Context {
+	return context.WithValue(ctx, ctxKey{}, p)
+}
+
+// FromContext возвращает пользователя запроса. ok == false для вызовов без аутентификации,
+// например при отключённой авторизации или во внутренних задачах сервиса.
+func FromContext(ctx context.Context) (p *Principal, ok bool) {
+	p, ok = ctx.Value(ctxKey{}).(*Principal)
+	return p, ok
+}
@@ -0,0 +1,211 @@
+package auth
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"github.com/golang-jwt/jwt/v5"
+	"math/big"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+)
+
+const testSecret = "test-secret"
+
+func sign(t *testing.T, method jwt.SigningMethod, key interface{}, kid string, claims jwt.MapClaims) string {
+	t.Helper()
+	token := jwt.NewWithClaims(method, claims)
+	if kid != "" {
+		token.Header["kid"] = kid
+	}
+	signed, err := token.SignedString(key)
+	if err != nil {
+		t.Fatalf("sign token: %v", err)
+	}
+	return signed
+}
+
+func validClaims() jwt.MapClaims {
+	return jwt.MapClaims{
+		"sub":   "11111111-1111-1111-1111-111111111111",
+		"roles": []string{"admin"},
+		"exp":   time.Now().Add(time.Hour).Unix(),
+		"iss":   "issuer",
+		"aud":   "subscriptions",
+	}
+}
+
+func withClaim(name string, value interface{}) jwt.MapClaims {
+	claims := validClaims()
+	if value == nil {
+		delete(claims, name)
+	} else {
+		claims[name] = value
+	}
+	return claims
+}
+
+func TestVerify(t *testing.T) {
+	v, err := NewVerifier(testSecret, "", "issuer", "subscriptions")
+	if err != nil {
+		t.Fatalf("NewVerifier() error: %v", err)
+	}
+	tests := []struct {
+		name    string
+		token   string
+		wantErr bool
+	}{
+		{name: "valid", token: sign(t, jwt.SigningMethodHS256, []byte(testSecret), "", validClaims())},
+		{name: "wrong secret", token: sign(t, jwt.SigningMethodHS256, []byte("other"), "", validClaims()), wantErr: true},
+		{name: "expired", token: sign(t, jwt.SigningMethodHS256, []byte(testSecret), "", withClaim("exp", time.Now().Add(-time.Minute).Unix())), wantErr: true},
+		{name: "missing exp", token: sign(t, jwt.SigningMethodHS256, []byte(testSecret), "", withClaim("exp", nil)), wantErr: true},
+		{name: "missing sub", token: sign(t, jwt.SigningMethodHS256, []byte(testSecret), "", withClaim("sub", nil)), wantErr: true},
+		{name: "wrong issuer", token: sign(t, jwt.SigningMethodHS256, []byte(testSecret), "", withClaim("iss", "someone-else")), wantErr: true},
+		{name: "wrong audience", token: sign(t, jwt.SigningMethodHS256, []byte(testSecret), "", withClaim("aud", "billing")), wantErr: true},
+		{name: "HS512 not allowed", token: sign(t, jwt.SigningMethodHS512, []byte(testSecret), "", validClaims()), wantErr: true},
+		{name: "unsigned", token: sign(t, jwt.SigningMethodNone, jwt.UnsafeAllowNoneSignatureType, "", validClaims()), wantErr: true},
+		{name: "garbage", token: "not.a.token", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p, err := v.Verify(tt.token)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("Verify() = %+v, want error", p)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Verify() error: %v", err)
+			}
+			if p.UserID != "11111111-1111-1111-1111-111111111111" || !slices.Equal(p.Roles, []string{"admin"}) {
+				t.Errorf("Verify() = %+v, want sub and roles from claims", p)
+			}
+		})
+	}
+}
+
+func TestNewVerifierRequiresKeys(t *testing.T) {
+	if _, err := NewVerifier("", "", "", ""); err == nil {
+		t.Error("NewVerifier() without secret and JWKS: want error")
+	}
+}
+
+// writeJWKS сохраняет открытые ключи в JWKS-файл и возвращает путь к нему.
+func writeJWKS(t *testing.T, keys []map[string]string) string {
+	t.Helper()
+	data, err := json.Marshal(map[string]interface{}{"keys": keys})
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(t.TempDir(), "jwks.json")
+	if err := os.WriteFile(path, data, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func rsaJWK(kid, use string, key *rsa.PublicKey) map[string]string {
+	return map[string]string{
+		"kty": "RSA",
+		"kid": kid,
+		"use": use,
+		"n":   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+		"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+	}
+}
+
+func TestVerifyRS256(t *testing.T) {
+	first, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := writeJWKS(t, []map[string]string{
+		rsaJWK("first", "sig", &first.PublicKey),
+		rsaJWK("second", "", &second.PublicKey),
+		rsaJWK("encryption", "enc", &first.PublicKey),
+	})
+	v, err := NewVerifier("", path, "", "")
+	if err != nil {
+		t.Fatalf("NewVerifier() error: %v", err)
+	}
+	tests := []struct {
+		name    string
+		token   string
+		wantErr bool
+	}{
+		{name: "first key", token: sign(t, jwt.SigningMethodRS256, first, "first", validClaims())},
+		{name: "second key", token: sign(t, jwt.SigningMethodRS256, second, "second", validClaims())},
+		{name: "kid of another key", token: sign(t, jwt.SigningMethodRS256, first, "second", validClaims()), wantErr: true},
+		{name: "unknown kid", token: sign(t, jwt.SigningMethodRS256, first, "third", validClaims()), wantErr: true},
+		{name: "encryption key is ignored", token: sign(t, jwt.SigningMethodRS256, first, "encryption", validClaims()), wantErr: true},
+		{name: "no kid with several keys", token: sign(t, jwt.SigningMethodRS256, first, "", validClaims()), wantErr: true},
+		{name: "HS256 without secret", token: sign(t, jwt.SigningMethodHS256, []byte(testSecret), "", validClaims()), wantErr: true},
+		{name: "missing exp", token: sign(t, jwt.SigningMethodRS256, first, "first", withClaim("exp", nil)), wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := v.Verify(tt.token)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Verify() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+
+	single, err := NewVerifier("", writeJWKS(t, []map[string]string{rsaJWK("only", "sig", &first.PublicKey)}), "", "")
+	if err != nil {
+		t.Fatalf("NewVerifier(single key) error: %v", err)
+	}
+	if _, err := single.Verify(sign(t, jwt.SigningMethodRS256, first, "", validClaims())); err != nil {
+		t.Errorf("Verify(no kid, single key) error: %v", err)
+	}
+}
+
+func TestLoadJWKS(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	badExponent := rsaJWK("bad", "sig", &key.PublicKey)
+	badExponent["e"] = base64.RawURLEncoding.EncodeToString([]byte{1})
+	tests := []struct {
+		name    string
+		keys    []map[string]string
+		wantErr bool
+	}{
+		{name: "rsa signing key", keys: []map[string]string{rsaJWK("k1", "sig", &key.PublicKey)}},
+		{name: "no keys", keys: []map[string]string{}, wantErr: true},
+		{name: "only encryption keys", keys: []map[string]string{rsaJWK("k1", "enc", &key.PublicKey)}, wantErr: true},
+		{name: "not rsa", keys: []map[string]string{{"kty": "EC", "kid": "k1"}}, wantErr: true},
+		{name: "bad modulus", keys: []map[string]string{{"kty": "RSA", "kid": "k1", "n": "!!", "e": "AQAB"}}, wantErr: true},
+		{name: "bad exponent", keys: []map[string]string{badExponent}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			keys, err := loadJWKS(writeJWKS(t, tt.keys))
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("loadJWKS() = %v, want error", keys)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("loadJWKS() error: %v", err)
+			}
+			if got := keys["k1"]; got == nil || got.N.Cmp(key.N) != 0 || got.E != key.E {
+				t.Errorf("loadJWKS() = %v, want key k1", keys)
+			}
+		})
+	}
+	if _, err := loadJWKS(filepath.Join(t.TempDir(), "missing.json")); err == nil {
+		t.Error("loadJWKS(missing file): want error")
+	}
+}
@@ -0,0 +1,122 @@
+package auth
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/Tommych123/subscription-service/models"
+	"os"
+	"slices"
+	"sort"
+	"strings"
+)
+
+// Роли пользователей.
+const (
+	RoleViewer = "viewer"
+	RoleEditor = "editor"
+	RoleAdmin  = "admin"
+)
+
+// Права. Права на подписки и итоги совпадают с областями доступа API-ключей.
+const (
+	PermSubscriptionsRead  = models.ScopeSubscriptionsRead
+	PermSubscriptionsWrite = models.ScopeSubscriptionsWrite
+	PermTotalsRead         = models.ScopeTotalsRead
+	// PermAllUsers разрешает работать с подписками любых пользователей, а не только со своими.
+	PermAllUsers = "users:all"
+	// PermAdmin открывает служебные эндпоинты /admin.
+	PermAdmin = "admin"
+)
+
+var permissions = map[string]bool{
+	PermSubscriptionsRead:  true,
+	PermSubscriptionsWrite: true,
+	PermTotalsRead:         true,
+	PermAllUsers:           true,
+	PermAdmin:              true,
+}
+
+// Policy сопоставляет ролям наборы прав. DefaultRoles назначаются пользователю,
+// в токене которого нет ролей.
+type Policy struct {
+	DefaultRoles []string            `json:"default_roles"`
+	Roles        map[string][]string `json:"roles"`
+}
+
+// DefaultPolicy — политика по умолчанию: viewer читает свои подписки и итоги, editor
+// ещё и изменяет свои подписки, admin работает с данными всех пользователей и эндпоинтами /admin.
+// Пользователь без ролей получает только viewer: право на запись выдаётся ролью в токене явно.
+func DefaultPolicy() *Policy {
+	return &Policy{
+		DefaultRoles: []string{RoleViewer},
+		Roles: map[string][]string{
+			RoleViewer: {PermSubscriptionsRead, PermTotalsRead},
+			RoleEditor: {PermSubscriptionsRead, PermTotalsRead, PermSubscriptionsWrite},
+			RoleAdmin:  {PermSubscriptionsRead, PermTotalsRead, PermSubscriptionsWrite, PermAllUsers, PermAdmin},
+		},
+	}
+}
+
+// LoadPolicy читает политику из JSON-файла в формате Policy и проверяет, что все права известны.
+func LoadPolicy(path string) (*Policy, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	var p Policy
+	dec := json.NewDecoder(f)
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&p); err != nil {
+		return nil, fmt.Errorf("parse policy %s: %w", path, err)
+	}
+	if len(p.Roles) == 0 {
+		return nil, errors.New("policy defines no roles")
+	}
+	for role, perms := range p.Roles {
+		for _, perm := range perms {
+			if !permissions[perm] {
+				return nil, fmt.Errorf("role %q: unknown permission %q", role, perm)
+			}
+		}
+	}
+	for _, role := range p.DefaultRoles {
+		if _, ok := p.Roles[role]; !ok {
+			return nil, fmt.Errorf("default role %q is not defined", role)
+		}
+	}
+	return &p, nil
+}
+
+// Apply назначает пользователю права его ролей; пользователь без ролей получает DefaultRoles.
+// Неизвестные роли прав не дают.
+func (p *Policy) Apply(principal *Principal) {
+	if len(principal.Roles) == 0 {
+		principal.Roles = slices.Clone(p.DefaultRoles)
+	}
+	var perms []string
+	for _, role := range principal.Roles {
+		for _, perm := range p.Roles[role] {
+			if !slices.Contains(perms, perm) {
+				perms = append(perms, perm)
+			}
+		}
+	}
+	sort.Strings(perms)
+	principal.Permissions = perms
+}
+
+// Authorize возвращает причину отказа, если у вызывающего нет права perm, и nil в противном случае.
+func Authorize(p *Principal, perm string) error {
+	if p.Can(perm) {
+		return nil
+	}
+	if p.IsService() {
+		return fmt.Errorf("api key %s has no %q scope", p.APIKeyID, perm)
+	}
+	if len(p.Roles) == 0 {
+		return fmt.Errorf("user %s has no roles", p.UserID)
+	}
+	return fmt.Errorf("roles [%s] of user %s do not grant %q", strings.Join(p.Roles, ", "), p.UserID, perm)
+}
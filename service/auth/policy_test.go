@@ -0,0 +1,114 @@
+package auth
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestPolicyApply(t *testing.T) {
+	policy := DefaultPolicy()
+	tests := []struct {
+		name      string
+		roles     []string
+		wantRoles []string
+		wantPerms []string
+	}{
+		{name: "no roles", wantRoles: []string{RoleViewer}, wantPerms: []string{PermSubscriptionsRead, PermTotalsRead}},
+		{name: "editor", roles: []string{RoleEditor}, wantRoles: []string{RoleEditor}, wantPerms: []string{PermSubscriptionsRead, PermSubscriptionsWrite, PermTotalsRead}},
+		{name: "admin", roles: []string{RoleAdmin}, wantRoles: []string{RoleAdmin}, wantPerms: []string{PermAdmin, PermSubscriptionsRead, PermSubscriptionsWrite, PermTotalsRead, PermAllUsers}},
+		{name: "overlapping roles", roles: []string{RoleViewer, RoleEditor}, wantRoles: []string{RoleViewer, RoleEditor}, wantPerms: []string{PermSubscriptionsRead, PermSubscriptionsWrite, PermTotalsRead}},
+		{name: "unknown role", roles: []string{"owner"}, wantRoles: []string{"owner"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := &Principal{UserID: "11111111-1111-1111-1111-111111111111", Roles: slices.Clone(tt.roles)}
+			policy.Apply(p)
+			if !slices.Equal(p.Roles, tt.wantRoles) || !slices.Equal(p.Permissions, tt.wantPerms) {
+				t.Errorf("Apply() = roles %v, permissions %v, want %v, %v", p.Roles, p.Permissions, tt.wantRoles, tt.wantPerms)
+			}
+		})
+	}
+
+	p := &Principal{}
+	policy.Apply(p)
+	p.Roles[0] = "changed"
+	if policy.DefaultRoles[0] != RoleViewer {
+		t.Error("Apply() shares DefaultRoles with the principal")
+	}
+}
+
+func TestAuthorize(t *testing.T) {
+	tests := []struct {
+		name    string
+		p       *Principal
+		perm    string
+		wantErr string
+	}{
+		{name: "granted", p: &Principal{UserID: "u1", Roles: []string{RoleViewer}, Permissions: []string{PermTotalsRead}}, perm: PermTotalsRead},
+		{name: "api key without scope", p: &Principal{APIKeyID: "k1", Permissions: []string{PermTotalsRead}}, perm: PermSubscriptionsWrite, wantErr: `api key k1 has no "subscriptions:write" scope`},
+		{name: "user without roles", p: &Principal{UserID: "u1"}, perm: PermTotalsRead, wantErr: "user u1 has no roles"},
+		{name: "roles do not grant", p: &Principal{UserID: "u1", Roles: []string{RoleViewer, RoleEditor}}, perm: PermAdmin, wantErr: `roles [viewer, editor] of user u1 do not grant "admin"`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := Authorize(tt.p, tt.perm)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("Authorize() error: %v", err)
+				}
+				return
+			}
+			if err == nil || err.Error() != tt.wantErr {
+				t.Errorf("Authorize() error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestLoadPolicy(t *testing.T) {
+	tests := []struct {
+		name    string
+		policy  string
+		wantErr string
+	}{
+		{name: "valid", policy: `{"default_roles": ["reader"], "roles": {"reader": ["subscriptions:read"], "ops": ["admin"]}}`},
+		{name: "no roles", policy: `{"default_roles": []}`, wantErr: "no roles"},
+		{name: "unknown permission", policy: `{"roles": {"reader": ["subscriptions:delete"]}}`, wantErr: "unknown permission"},
+		{name: "undefined default role", policy: `{"default_roles": ["viewer"], "roles": {"reader": ["subscriptions:read"]}}`, wantErr: "is not defined"},
+		{name: "unknown field", policy: `{"role": {"reader": ["subscriptions:read"]}}`, wantErr: "unknown field"},
+		{name: "not json", policy: `roles: reader`, wantErr: "parse policy"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "policy.json")
+			if err := os.WriteFile(path, []byte(tt.policy), 0o600); err != nil {
+				t.Fatal(err)
+			}
+			p, err := LoadPolicy(path)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("LoadPolicy() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("LoadPolicy() error: %v", err)
+			}
+			ops := &Principal{Roles: []string{"ops"}}
+			p.Apply(ops)
+			if !slices.Equal(ops.Permissions, []string{PermAdmin}) {
+				t.Errorf("ops permissions = %v, want [admin]", ops.Permissions)
+			}
+		})
+	}
+
+	if _, err := LoadPolicy(filepath.Join("..", "..", "deploy", "rbac-policy.json")); err != nil {
+		t.Errorf("LoadPolicy(deploy example) error: %v", err)
+	}
+	if _, err := LoadPolicy(filepath.Join(t.TempDir(), "missing.json")); err == nil {
+		t.Error("LoadPolicy(missing file): want error")
+	}
+}
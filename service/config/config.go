@@ -1,12 +1,18 @@
 package config
 
 import (
+	"github.com/Tommych123/subscription-service/internal/ratelimit"
 	"github.com/joho/godotenv"
 	"go.uber.org/zap"
 	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
 )
 
 type Config struct {
+	Storage    string
 	DBHost     string
 	DBPort     string
 	DBUser     string
@@ -14,6 +20,53 @@ type Config struct {
 	DBName     string
 	DBSSLMode  string
 	ServerPort string
+	// QueryTimeout ограничивает время обработки одного запроса к API вместе с запросами к БД.
+	QueryTimeout time.Duration
+	// Таймауты http.Server: чтение запроса, чтение заголовков, запись ответа и простой keep-alive соединения.
+	ReadTimeout       time.Duration
+	ReadHeaderTimeout time.Duration
+	WriteTimeout      time.Duration
+	IdleTimeout       time.Duration
+	// ShutdownTimeout — сколько ждать завершения активных запросов после SIGTERM.
+	ShutdownTimeout time.Duration
+	// TracingExporter — куда отправлять трейсы OpenTelemetry: none, stdout или otlp.
+	TracingExporter string
+	ServiceName     string
+	// AccessLogSampleRate — доля успешных запросов (0..1), попадающих в журнал доступа;
+	// ответы с кодом 4xx и 5xx пишутся всегда.
+	AccessLogSampleRate float64
+	// AccessLogSkipPaths — пути, запросы к которым не пишутся в журнал доступа.
+	AccessLogSkipPaths []string
+	// LogLevel, LogFormat и LogOutput — уровень (debug, info, warn, error), формат (json, console)
+	// и вывод (stdout, stderr или путь к файлу) логов приложения.
+	LogLevel  string
+	LogFormat string
+	LogOutput string
+	// AuthDisabled отключает аутентификацию и эндпоинты /admin; только для локальной разработки.
+	AuthDisabled bool
+	// Проверка JWT: общий секрет для HS256 и/или JWKS-файл с открытыми ключами для RS256.
+	// Без AuthDisabled должно быть задано хотя бы одно из них. JWTIssuer и JWTAudience
+	// проверяются, если заданы.
+	JWTSecret   string
+	JWKSFile    string
+	JWTIssuer   string
+	JWTAudience string
+	// PolicyFile — JSON-файл с правами ролей; если не задан, действует auth.DefaultPolicy.
+	PolicyFile string
+	// DefaultTenant — арендатор запросов без X-Tenant-ID и токенов без claim tenant_id.
+	// TenantRLS включает проверку арендатора политиками row-level security PostgreSQL.
+	DefaultTenant string
+	TenantRLS     bool
+	// RateLimitDefault — лимит запросов одного клиента (API-ключа, пользователя или IP) к API.
+	// RateLimitRoutes задаёт отдельные лимиты для шаблонов маршрутов, например "/total";
+	// у каждого такого маршрута своё ведро, остальные маршруты делят общее.
+	RateLimitDefault ratelimit.Limit
+	RateLimitRoutes  map[string]ratelimit.Limit
+	// RateLimitIP — лимит всех запросов с одного IP, проверяемый до аутентификации.
+	RateLimitIP ratelimit.Limit
+	// TrustedProxies — адреса или подсети прокси, которым доверяется X-Forwarded-For при
+	// определении IP клиента. Без них IP берётся из адреса соединения.
+	TrustedProxies []string
 }
 
 func LoadConfig(log *zap.Logger) *Config {
@@ -23,21 +76,76 @@ func LoadConfig(log *zap.Logger) *Config {
 		log.Info(".env file loaded successfully")
 	}
 	cfg := &Config{
-		DBHost:     getEnv(log, "DB_HOST", "localhost"),
-		DBPort:     getEnv(log, "DB_PORT", "5432"),
-		DBUser:     getEnv(log, "DB_USER", "postgres"),
-		DBPassword: getEnv(log, "DB_PASSWORD", "postgres"),
-		DBName:     getEnv(log, "DB_NAME", "subscriptions"),
-		DBSSLMode:  getEnv(log, "DB_SSLMODE", "disable"),
-		ServerPort: getEnv(log, "SERVER_PORT", "8080"),
+		Storage:             getEnv(log, "STORAGE", "postgres"),
+		DBHost:              getEnv(log, "DB_HOST", "localhost"),
+		DBPort:              getEnv(log, "DB_PORT", "5432"),
+		DBUser:              getEnv(log, "DB_USER", "postgres"),
+		DBPassword:          getEnv(log, "DB_PASSWORD", "postgres"),
+		DBName:              getEnv(log, "DB_NAME", "subscriptions"),
+		DBSSLMode:           getEnv(log, "DB_SSLMODE", "disable"),
+		ServerPort:          getEnv(log, "SERVER_PORT", "8080"),
+		QueryTimeout:        getDuration(log, "QUERY_TIMEOUT", 10*time.Second),
+		ReadTimeout:         getDuration(log, "HTTP_READ_TIMEOUT", 15*time.Second),
+		ReadHeaderTimeout:   getDuration(log, "HTTP_READ_HEADER_TIMEOUT", 5*time.Second),
+		WriteTimeout:        getDuration(log, "HTTP_WRITE_TIMEOUT", 30*time.Second),
+		IdleTimeout:         getDuration(log, "HTTP_IDLE_TIMEOUT", 60*time.Second),
+		ShutdownTimeout:     getDuration(log, "SHUTDOWN_TIMEOUT", 20*time.Second),
+		TracingExporter:     getEnv(log, "TRACING_EXPORTER", "none"),
+		ServiceName:         getEnv(log, "OTEL_SERVICE_NAME", "subscription-service"),
+		AccessLogSampleRate: getFloat(log, "ACCESS_LOG_SAMPLE_RATE", 1),
+		AccessLogSkipPaths:  getList(log, "ACCESS_LOG_SKIP_PATHS", []string{"/healthz", "/readyz", "/metrics"}),
+		LogLevel:            getEnv(log, "LOG_LEVEL", "info"),
+		LogFormat:           getEnv(log, "LOG_FORMAT", "json"),
+		LogOutput:           getEnv(log, "LOG_OUTPUT", "stderr"),
+		AuthDisabled:        getBool(log, "AUTH_DISABLED", false),
+		JWTSecret:           os.Getenv("JWT_SECRET"),
+		JWKSFile:            getEnv(log, "JWT_JWKS_FILE", ""),
+		JWTIssuer:           getEnv(log, "JWT_ISSUER", ""),
+		JWTAudience:         getEnv(log, "JWT_AUDIENCE", ""),
+		PolicyFile:          getEnv(log, "RBAC_POLICY_FILE", ""),
+		DefaultTenant:       getEnv(log, "DEFAULT_TENANT", "default"),
+		TenantRLS:           getBool(log, "TENANT_RLS", false),
+		RateLimitDefault:    getLimit(log, "RATE_LIMIT_DEFAULT", ratelimit.Limit{Requests: 300, Period: time.Minute}),
+		RateLimitRoutes: getRouteLimits(log, "RATE_LIMIT_ROUTES", map[string]ratelimit.Limit{
+			"/total":           {Requests: 30, Period: time.Minute},
+			"/total/breakdown": {Requests: 30, Period: time.Minute},
+		}),
+		RateLimitIP:    getLimit(log, "RATE_LIMIT_IP", ratelimit.Limit{Requests: 600, Period: time.Minute}),
+		TrustedProxies: getList(log, "TRUSTED_PROXIES", []string{}),
 	}
 	log.Info("Config loaded",
+		zap.String("Storage", cfg.Storage),
 		zap.String("DBHost", cfg.DBHost),
 		zap.String("DBPort", cfg.DBPort),
 		zap.String("DBUser", cfg.DBUser),
 		zap.String("DBName", cfg.DBName),
 		zap.String("DBSSLMode", cfg.DBSSLMode),
 		zap.String("ServerPort", cfg.ServerPort),
+		zap.Duration("QueryTimeout", cfg.QueryTimeout),
+		zap.Duration("ReadTimeout", cfg.ReadTimeout),
+		zap.Duration("ReadHeaderTimeout", cfg.ReadHeaderTimeout),
+		zap.Duration("WriteTimeout", cfg.WriteTimeout),
+		zap.Duration("IdleTimeout", cfg.IdleTimeout),
+		zap.Duration("ShutdownTimeout", cfg.ShutdownTimeout),
+		zap.String("TracingExporter", cfg.TracingExporter),
+		zap.String("ServiceName", cfg.ServiceName),
+		zap.Float64("AccessLogSampleRate", cfg.AccessLogSampleRate),
+		zap.Strings("AccessLogSkipPaths", cfg.AccessLogSkipPaths),
+		zap.String("LogLevel", cfg.LogLevel),
+		zap.String("LogFormat", cfg.LogFormat),
+		zap.String("LogOutput", cfg.LogOutput),
+		zap.Bool("AuthDisabled", cfg.AuthDisabled),
+		zap.Bool("JWTSecretSet", cfg.JWTSecret != ""),
+		zap.String("JWKSFile", cfg.JWKSFile),
+		zap.String("JWTIssuer", cfg.JWTIssuer),
+		zap.String("JWTAudience", cfg.JWTAudience),
+		zap.String("PolicyFile", cfg.PolicyFile),
+		zap.String("DefaultTenant", cfg.DefaultTenant),
+		zap.Bool("TenantRLS", cfg.TenantRLS),
+		zap.Stringer("RateLimitDefault", cfg.RateLimitDefault),
+		zap.Strings("RateLimitRoutes", formatRouteLimits(cfg.RateLimitRoutes)),
+		zap.Stringer("RateLimitIP", cfg.RateLimitIP),
+		zap.Strings("TrustedProxies", cfg.TrustedProxies),
 	)
 	return cfg
 }
@@ -49,3 +157,108 @@ func getEnv(log *zap.Logger, key, fallback string) string {
 	log.Warn("Environment variable not set, using default", zap.String("key", key), zap.String("default", fallback))
 	return fallback
 }
+
+func getDuration(log *zap.Logger, key string, fallback time.Duration) time.Duration {
+	val := os.Getenv(key)
+	if val == "" {
+		log.Warn("Environment variable not set, using default", zap.String("key", key), zap.Duration("default", fallback))
+		return fallback
+	}
+	d, err := time.ParseDuration(val)
+	if err != nil || d <= 0 {
+		log.Warn("Invalid duration in environment variable, using default", zap.String("key", key), zap.String("value", val), zap.Duration("default", fallback))
+		return fallback
+	}
+	return d
+}
+
+func getFloat(log *zap.Logger, key string, fallback float64) float64 {
+	val := os.Getenv(key)
+	if val == "" {
+		log.Warn("Environment variable not set, using default", zap.String("key", key), zap.Float64("default", fallback))
+		return fallback
+	}
+	f, err := strconv.ParseFloat(val, 64)
+	if err != nil || f < 0 || f > 1 {
+		log.Warn("Invalid ratio in environment variable, using default", zap.String("key", key), zap.String("value", val), zap.Float64("default", fallback))
+		return fallback
+	}
+	return f
+}
+
+func getBool(log *zap.Logger, key string, fallback bool) bool {
+	val := os.Getenv(key)
+	if val == "" {
+		log.Warn("Environment variable not set, using default", zap.String("key", key), zap.Bool("default", fallback))
+		return fallback
+	}
+	b, err := strconv.ParseBool(val)
+	if err != nil {
+		log.Warn("Invalid boolean in environment variable, using default", zap.String("key", key), zap.String("value", val), zap.Bool("default", fallback))
+		return fallback
+	}
+	return b
+}
+
+// getList разбирает список значений через запятую; пустые элементы отбрасываются.
+func getList(log *zap.Logger, key string, fallback []string) []string {
+	val, ok := os.LookupEnv(key)
+	if !ok {
+		log.Warn("Environment variable not set, using default", zap.String("key", key), zap.Strings("default", fallback))
+		return fallback
+	}
+	items := []string{}
+	for _, item := range strings.Split(val, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+func getLimit(log *zap.Logger, key string, fallback ratelimit.Limit) ratelimit.Limit {
+	val, ok := os.LookupEnv(key)
+	if !ok {
+		log.Warn("Environment variable not set, using default", zap.String("key", key), zap.Stringer("default", fallback))
+		return fallback
+	}
+	limit, err := ratelimit.ParseLimit(val)
+	if err != nil {
+		log.Warn("Invalid rate limit in environment variable, using default", zap.String("key", key), zap.Error(err), zap.Stringer("default", fallback))
+		return fallback
+	}
+	return limit
+}
+
+// getRouteLimits разбирает лимиты маршрутов вида "/total=30/1m,/total/breakdown=10/1m".
+// Некорректные элементы пропускаются с предупреждением.
+func getRouteLimits(log *zap.Logger, key string, fallback map[string]ratelimit.Limit) map[string]ratelimit.Limit {
+	val, ok := os.LookupEnv(key)
+	if !ok {
+		log.Warn("Environment variable not set, using default", zap.String("key", key), zap.Strings("default", formatRouteLimits(fallback)))
+		return fallback
+	}
+	limits := make(map[string]ratelimit.Limit)
+	for _, item := range strings.Split(val, ",") {
+		if item = strings.TrimSpace(item); item == "" {
+			continue
+		}
+		route, spec, ok := strings.Cut(item, "=")
+		limit, err := ratelimit.ParseLimit(spec)
+		if !ok || err != nil {
+			log.Warn("Invalid route rate limit in environment variable, skipping", zap.String("key", key), zap.String("value", item))
+			continue
+		}
+		limits[strings.TrimSpace(route)] = limit
+	}
+	return limits
+}
+
+func formatRouteLimits(limits map[string]ratelimit.Limit) []string {
+	items := make([]string, 0, len(limits))
+	for route, limit := range limits {
+		items = append(items, route+"="+limit.String())
+	}
+	sort.Strings(items)
+	return items
+}
@@ -0,0 +1,196 @@
+package service
+
+import (
+	"context"
+	"github.com/Tommych123/subscription-service/models"
+	"github.com/Tommych123/subscription-service/repository"
+	"go.uber.org/zap"
+	"math/big"
+	"slices"
+	"testing"
+	"time"
+)
+
+// monthlyCosts возвращает помесячные суммы разбивки без группировки.
+func monthlyCosts(t *testing.T, s *SubscriptionService, serviceName, from, to string) []models.Money {
+	t.Helper()
+	breakdown, err := s.GetCostBreakdown(context.Background(), "", serviceName, month(t, from).Time, month(t, to).Time, "", models.DefaultCurrency)
+	if err != nil {
+		t.Fatalf("GetCostBreakdown(%s) error: %v", serviceName, err)
+	}
+	costs := []models.Money{}
+	var total models.Money
+	for _, e := range breakdown.Items {
+		costs = append(costs, e.Cost)
+		total += e.Cost
+	}
+	if breakdown.TotalCost != total {
+		t.Errorf("TotalCost = %s, want sum of items %s", breakdown.TotalCost, total)
+	}
+	return costs
+}
+
+func TestCostBreakdownBillingPeriods(t *testing.T) {
+	s := newTestService(t)
+	for _, sub := range []models.Subscription{
+		{ServiceName: "weekly", Price: 100, BillingPeriod: models.BillingWeekly},
+		{ServiceName: "monthly", Price: 1000, BillingPeriod: models.BillingMonthly},
+		{ServiceName: "quarterly", Price: 3000, BillingPeriod: models.BillingQuarterly},
+		{ServiceName: "annual", Price: 12000, BillingPeriod: models.BillingAnnual},
+	} {
+		sub.UserID = alice
+		sub.StartDate = month(t, "01-2025")
+		sub.EndDate = monthPtr(t, "06-2025")
+		create(t, s, sub)
+	}
+	tests := []struct {
+		serviceName string
+		from, to    string
+		want        []models.Money
+	}{
+		{serviceName: "weekly", from: "01-2025", to: "06-2025", want: []models.Money{500, 400, 400, 500, 400, 400}},
+		{serviceName: "monthly", from: "01-2025", to: "06-2025", want: []models.Money{1000, 1000, 1000, 1000, 1000, 1000}},
+		{serviceName: "quarterly", from: "01-2025", to: "06-2025", want: []models.Money{3000, 0, 0, 3000, 0, 0}},
+		{serviceName: "annual", from: "01-2025", to: "06-2025", want: []models.Money{12000, 0, 0, 0, 0, 0}},
+		// Период после окончания подписки и до её начала заполняется нулями.
+		{serviceName: "monthly", from: "11-2024", to: "08-2025", want: []models.Money{0, 0, 1000, 1000, 1000, 1000, 1000, 1000, 0, 0}},
+		// Квартальное списание считается с start_date, а не с начала запрошенного периода.
+		{serviceName: "quarterly", from: "02-2025", to: "05-2025", want: []models.Money{0, 0, 3000, 0}},
+		{serviceName: "annual", from: "02-2025", to: "06-2025", want: []models.Money{0, 0, 0, 0, 0}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.serviceName+" "+tt.from+".."+tt.to, func(t *testing.T) {
+			if got := monthlyCosts(t, s, tt.serviceName, tt.from, tt.to); !slices.Equal(got, tt.want) {
+				t.Errorf("monthly costs = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCostBreakdownPriceHistory(t *testing.T) {
+	s := newTestService(t)
+	ctx := context.Background()
+	monthly := create(t, s, models.Subscription{ServiceName: "monthly", Price: 1000, UserID: alice, StartDate: month(t, "01-2025"), EndDate: monthPtr(t, "06-2025")})
+	quarterly := create(t, s, models.Subscription{ServiceName: "quarterly", Price: 3000, UserID: alice, StartDate: month(t, "01-2025"), EndDate: monthPtr(t, "12-2025"), BillingPeriod: models.BillingQuarterly})
+	for _, change := range []struct {
+		id     string
+		change models.PriceChange
+	}{
+		{monthly, models.PriceChange{Price: 1500, EffectiveFrom: month(t, "03-2025")}},
+		{monthly, models.PriceChange{Price: 2000, EffectiveFrom: month(t, "05-2025")}},
+		{quarterly, models.PriceChange{Price: 4500, EffectiveFrom: month(t, "05-2025")}},
+	} {
+		if err := s.SchedulePrice(ctx, change.id, change.change); err != nil {
+			t.Fatalf("SchedulePrice(%+v) error: %v", change.change, err)
+		}
+	}
+
+	if got, want := monthlyCosts(t, s, "monthly", "01-2025", "06-2025"), []models.Money{1000, 1000, 1500, 1500, 2000, 2000}; !slices.Equal(got, want) {
+		t.Errorf("monthly costs = %v, want %v", got, want)
+	}
+	if got, want := monthlyCosts(t, s, "quarterly", "01-2025", "12-2025"), []models.Money{3000, 0, 0, 3000, 0, 0, 4500, 0, 0, 4500, 0, 0}; !slices.Equal(got, want) {
+		t.Errorf("quarterly costs = %v, want %v", got, want)
+	}
+
+	// Новая цена не меняет итог за месяцы до неё.
+	total, err := s.GetTotalCost(ctx, "", "", month(t, "01-2025").Time, month(t, "02-2025").Time, models.DefaultCurrency)
+	if err != nil {
+		t.Fatalf("GetTotalCost() error: %v", err)
+	}
+	if total != 5000 {
+		t.Errorf("GetTotalCost(01-2025..02-2025) = %s, want 50.00", total)
+	}
+}
+
+func TestCostBreakdownCurrencyConversion(t *testing.T) {
+	rates := repository.NewMemoryExchangeRateRepository()
+	if err := rates.SetRate("USD", models.DefaultCurrency, month(t, "01-2025").Time, big.NewRat(90, 1)); err != nil {
+		t.Fatal(err)
+	}
+	if err := rates.SetRate("USD", models.DefaultCurrency, month(t, "03-2025").Time, big.NewRat(95, 1)); err != nil {
+		t.Fatal(err)
+	}
+	s := NewSubscriptionService(repository.NewMemorySubscriptionRepository(), rates, zap.NewNop())
+	create(t, s, models.Subscription{ServiceName: "Netflix", Price: 1000, Currency: "USD", UserID: alice, StartDate: month(t, "01-2025"), EndDate: monthPtr(t, "04-2025")})
+	create(t, s, models.Subscription{ServiceName: "Spotify", Price: 40000, UserID: alice, StartDate: month(t, "01-2025"), EndDate: monthPtr(t, "04-2025")})
+
+	if got, want := monthlyCosts(t, s, "", "01-2025", "04-2025"), []models.Money{130000, 130000, 135000, 135000}; !slices.Equal(got, want) {
+		t.Errorf("monthly costs = %v, want %v", got, want)
+	}
+	breakdown, err := s.GetCostBreakdown(context.Background(), "", "", month(t, "01-2025").Time, month(t, "01-2025").Time, "", "USD")
+	if err != nil {
+		t.Fatalf("GetCostBreakdown(USD) error: %v", err)
+	}
+	if breakdown.TotalCost != 1444 {
+		t.Errorf("GetCostBreakdown(USD) total = %s, want 14.44", breakdown.TotalCost)
+	}
+	if _, err := s.GetTotalCost(context.Background(), "", "", month(t, "01-2025").Time, month(t, "01-2025").Time, "EUR"); err == nil {
+		t.Error("GetTotalCost(EUR) without a rate: want error")
+	}
+}
+
+// diffMonths — прежний расчёт числа месяцев подписки в периоде, до учёта периодов списания.
+func diffMonths(from, to time.Time) int {
+	from = time.Date(from.Year(), from.Month(), 1, 0, 0, 0, 0, time.UTC)
+	to = time.Date(to.Year(), to.Month(), 1, 0, 0, 0, 0, time.UTC)
+	months := int(to.Month()) - int(from.Month()) + 12*(to.Year()-from.Year()) + 1
+	if months < 0 {
+		return 0
+	}
+	return months
+}
+
+// legacyTotal — прежний итог: цена, умноженная на число месяцев подписки внутри периода.
+func legacyTotal(subs []models.Subscription, from, to time.Time) models.Money {
+	var total models.Money
+	for _, sub := range subs {
+		start := sub.StartDate.Time
+		end := time.Now()
+		if sub.EndDate != nil {
+			end = sub.EndDate.Time
+		}
+		if end.Before(from) || start.After(to) {
+			continue
+		}
+		if start.Before(from) {
+			start = from
+		}
+		if end.After(to) {
+			end = to
+		}
+		total += sub.Price * models.Money(diffMonths(start, end))
+	}
+	return total
+}
+
+func TestTotalCostMatchesLegacyForMonthly(t *testing.T) {
+	s := newTestService(t)
+	subs := []models.Subscription{
+		{ServiceName: "Spotify", Price: 40000, UserID: alice, StartDate: month(t, "07-2024"), EndDate: monthPtr(t, "03-2025")},
+		{ServiceName: "Netflix", Price: 79900, UserID: alice, StartDate: month(t, "01-2024")},
+		{ServiceName: "Yandex Plus", Price: 29900, UserID: bob, StartDate: month(t, "02-2025"), EndDate: monthPtr(t, "02-2025")},
+		{ServiceName: "iCloud", Price: 14900, UserID: bob, StartDate: month(t, "11-2024"), EndDate: monthPtr(t, "06-2025")},
+		{ServiceName: "Spotify", Price: 19900, UserID: bob, StartDate: month(t, "05-2023"), EndDate: monthPtr(t, "08-2023")},
+	}
+	for _, sub := range subs {
+		create(t, s, sub)
+	}
+	periods := [][2]string{
+		{"01-2024", "12-2024"},
+		{"01-2025", "12-2025"},
+		{"07-2024", "07-2024"},
+		{"03-2025", "03-2025"},
+		{"01-2023", "06-2025"},
+		{"09-2023", "12-2023"},
+	}
+	for _, p := range periods {
+		from, to := month(t, p[0]).Time, month(t, p[1]).Time
+		got, err := s.GetTotalCost(context.Background(), "", "", from, to, models.DefaultCurrency)
+		if err != nil {
+			t.Fatalf("GetTotalCost(%s..%s) error: %v", p[0], p[1], err)
+		}
+		if want := legacyTotal(subs, from, to); got != want {
+			t.Errorf("GetTotalCost(%s..%s) = %s, want %s", p[0], p[1], got, want)
+		}
+	}
+}
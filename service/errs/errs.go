@@ -0,0 +1,46 @@
+// Package errs содержит доменные ошибки сервиса подписок. Репозиторий возвращает их
+// (обёрнутыми через fmt.Errorf с %w), а HTTP-слой сопоставляет их с кодами ответа.
+package errs
+
+import (
+	"errors"
+	"strings"
+)
+
+var (
+	// ErrNotFound — запрошенная подписка не существует.
+	ErrNotFound = errors.New("not found")
+	// ErrInvalidID — идентификатор не является корректным UUID.
+	ErrInvalidID = errors.New("invalid id")
+	// ErrConflict — запись противоречит уже существующим данным.
+	ErrConflict = errors.New("conflict")
+	// ErrRateNotFound — для пересчёта итогов не хватает курса валюты.
+	ErrRateNotFound = errors.New("exchange rate not found")
+	// ErrInvalidInput — тело или параметры запроса не удалось разобрать.
+	ErrInvalidInput = errors.New("invalid input")
+	// ErrUnauthorized — запрос без действительных учётных данных.
+	ErrUnauthorized = errors.New("unauthorized")
+	// ErrForbidden — у вызывающего нет прав на операцию.
+	ErrForbidden = errors.New("forbidden")
+	// ErrRateLimited — клиент превысил лимит запросов.
+	ErrRateLimited = errors.New("rate limit exceeded")
+)
+
+// FieldError — ошибка проверки одного поля входных данных.
+type FieldError struct {
+	Field   string `json:"field" example:"price"`
+	Message string `json:"message" example:"must not be negative"`
+}
+
+// ValidationError содержит ошибки проверки по каждому полю.
+type ValidationError struct {
+	Fields []FieldError
+}
+
+func (e *ValidationError) Error() string {
+	msgs := make([]string, 0, len(e.Fields))
+	for _, f := range e.Fields {
+		msgs = append(msgs, f.Field+": "+f.Message)
+	}
+	return "validation failed: " + strings.Join(msgs, "; ")
+}
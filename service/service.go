@@ -1,124 +1,363 @@
 package service
 
 import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"github.com/Tommych123/subscription-service/internal/logger"
+	"github.com/Tommych123/subscription-service/internal/metrics"
 	"github.com/Tommych123/subscription-service/models"
-	"github.com/Tommych123/subscription-service/repository"
+	"github.com/Tommych123/subscription-service/service/errs"
+	"github.com/Tommych123/subscription-service/service/tenant"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 	"go.uber.org/zap"
+	"math/big"
+	"strconv"
 	"time"
 )
 
+// SubscriptionStore — хранилище подписок, с которым работает сервис. Реализации:
+// repository.SubscriptionRepository (PostgreSQL) и repository.MemorySubscriptionRepository (память процесса).
+// Методы возвращают доменные ошибки из пакета errs.
+type SubscriptionStore interface {
+	Create(ctx context.Context, sub *models.Subscription) (string, error)
+	GetByID(ctx context.Context, id string) (*models.Subscription, error)
+	Update(ctx context.Context, sub *models.Subscription) error
+	// Patch применяет patch к подписке, проверяет результат функцией check и сохраняет его
+	// атомарно: между проверкой и записью подписку никто не изменит.
+	Patch(ctx context.Context, id string, patch *models.SubscriptionPatch, check func(current, next *models.Subscription) error) (*models.Subscription, error)
+	Delete(ctx context.Context, id string) error
+	SchedulePrice(ctx context.Context, id string, change models.PriceChange) error
+	List(ctx context.Context, params models.ListParams) ([]models.Subscription, int, error)
+	// CostBreakdown возвращает суммы списаний по месяцам, исходным валютам и, при groupBy, по полю группировки.
+	CostBreakdown(ctx context.Context, userID, serviceName string, from, to time.Time, groupBy string) ([]models.CostEntry, error)
+}
+
+// ExchangeRateProvider возвращает курс пересчёта base→quote, действующий в указанном месяце.
+// ok == false означает, что курс для пары неизвестен.
+type ExchangeRateProvider interface {
+	Rate(ctx context.Context, base, quote models.Currency, month time.Time) (rate *big.Rat, ok bool, err error)
+}
+
 type SubscriptionService struct {
-	repo   *repository.SubscriptionRepository
+	repo   SubscriptionStore
+	rates  ExchangeRateProvider
 	logger *zap.Logger
+	now    func() time.Time
 }
 
-func NewSubscriptionService(repo *repository.SubscriptionRepository, logger *zap.Logger) *SubscriptionService {
+func NewSubscriptionService(repo SubscriptionStore, rates ExchangeRateProvider, logger *zap.Logger) *SubscriptionService {
 	return &SubscriptionService{
 		repo:   repo,
+		rates:  rates,
 		logger: logger,
+		now:    time.Now,
 	}
 }
 
-func (s *SubscriptionService) Create(sub *models.Subscription) (string, error) {
-	id, err := s.repo.Create(sub)
+// log возвращает логгер текущего запроса с его request_id, а вне запроса — логгер сервиса.
+func (s *SubscriptionService) log(ctx context.Context) *zap.Logger {
+	return logger.FromContext(ctx, s.logger)
+}
+
+func (s *SubscriptionService) Create(ctx context.Context, sub *models.Subscription) (id string, err error) {
+	ctx, span := tracer.Start(ctx, "SubscriptionService.Create", trace.WithAttributes(
+		attribute.String("user_id", sub.UserID),
+		attribute.String("service_name", sub.ServiceName),
+	))
+	defer func() { endSpan(span, err) }()
+	if err := validateSubscription(sub); err != nil {
+		return "", err
+	}
+	if err := authorizeUser(ctx, sub.UserID); err != nil {
+		return "", err
+	}
+	id, err = s.repo.Create(ctx, sub)
 	if err != nil {
-		s.logger.Error("Failed to create subscription", zap.Error(err), zap.Any("subscription", sub))
 		return "", err
 	}
-	s.logger.Info("Subscription created", zap.String("id", id))
+	s.log(ctx).Info("Subscription created", zap.String("id", id))
 	return id, nil
 }
 
-func (s *SubscriptionService) GetByID(id string) (*models.Subscription, error) {
-	sub, err := s.repo.GetByID(id)
+func (s *SubscriptionService) GetByID(ctx context.Context, id string) (sub *models.Subscription, err error) {
+	ctx, span := tracer.Start(ctx, "SubscriptionService.GetByID", trace.WithAttributes(attribute.String("subscription_id", id)))
+	defer func() { endSpan(span, err) }()
+	sub, err = s.getOwned(ctx, id)
 	if err != nil {
-		s.logger.Error("Failed to get subscription by ID", zap.Error(err), zap.String("id", id))
 		return nil, err
 	}
 	return sub, nil
 }
 
-func (s *SubscriptionService) Update(sub *models.Subscription) error {
-	err := s.repo.Update(sub)
+// Update обновляет подписку. Цена должна совпадать с действующей в текущем месяце.
+func (s *SubscriptionService) Update(ctx context.Context, sub *models.Subscription) (err error) {
+	ctx, span := tracer.Start(ctx, "SubscriptionService.Update", trace.WithAttributes(
+		attribute.String("subscription_id", sub.ID),
+		attribute.String("user_id", sub.UserID),
+		attribute.String("service_name", sub.ServiceName),
+	))
+	defer func() { endSpan(span, err) }()
+	if err := validateSubscription(sub); err != nil {
+		return err
+	}
+	current, err := s.getOwned(ctx, sub.ID)
 	if err != nil {
-		s.logger.Error("Failed to update subscription", zap.Error(err), zap.Any("subscription", sub))
 		return err
 	}
-	s.logger.Info("Subscription updated", zap.String("id", sub.ID))
+	if err := validatePriceUnchanged(current, sub); err != nil {
+		return err
+	}
+	if err := authorizeUser(ctx, sub.UserID); err != nil {
+		return err
+	}
+	err = s.repo.Update(ctx, sub)
+	if err != nil {
+		return err
+	}
+	s.log(ctx).Info("Subscription updated", zap.String("id", sub.ID))
 	return nil
 }
 
-func (s *SubscriptionService) Delete(id string) error {
-	err := s.repo.Delete(id)
+// Patch применяет частичное обновление; результат проверяется так же, как при полном обновлении.
+// Проверка и запись выполняются в одной транзакции репозитория над заблокированной строкой.
+func (s *SubscriptionService) Patch(ctx context.Context, id string, patch *models.SubscriptionPatch) (sub *models.Subscription, err error) {
+	ctx, span := tracer.Start(ctx, "SubscriptionService.Patch", trace.WithAttributes(attribute.String("subscription_id", id)))
+	defer func() { endSpan(span, err) }()
+	sub, err = s.repo.Patch(ctx, id, patch, func(current, next *models.Subscription) error {
+		// Чужая подписка для обычного пользователя выглядит как несуществующая, как в getOwned.
+		if authorizeUser(ctx, current.UserID) != nil {
+			return fmt.Errorf("subscription %s: %w", id, errs.ErrNotFound)
+		}
+		if err := validateSubscription(next); err != nil {
+			return err
+		}
+		if err := validatePriceUnchanged(current, next); err != nil {
+			return err
+		}
+		return authorizeUser(ctx, next.UserID)
+	})
 	if err != nil {
-		s.logger.Error("Failed to delete subscription", zap.Error(err), zap.String("id", id))
+		return nil, err
+	}
+	s.log(ctx).Info("Subscription patched", zap.String("id", id))
+	return sub, nil
+}
+
+func (s *SubscriptionService) Delete(ctx context.Context, id string) (err error) {
+	ctx, span := tracer.Start(ctx, "SubscriptionService.Delete", trace.WithAttributes(attribute.String("subscription_id", id)))
+	defer func() { endSpan(span, err) }()
+	if _, err := s.getOwned(ctx, id); err != nil {
 		return err
 	}
-	s.logger.Info("Subscription deleted", zap.String("id", id))
+	err = s.repo.Delete(ctx, id)
+	if err != nil {
+		return err
+	}
+	s.log(ctx).Info("Subscription deleted", zap.String("id", id))
 	return nil
 }
 
-func (s *SubscriptionService) List() ([]models.Subscription, error) {
-	subs, err := s.repo.List()
+// SchedulePrice назначает новую цену подписки с указанного месяца (не раньше текущего), не меняя уже прошедшие списания.
+func (s *SubscriptionService) SchedulePrice(ctx context.Context, id string, change models.PriceChange) (err error) {
+	ctx, span := tracer.Start(ctx, "SubscriptionService.SchedulePrice", trace.WithAttributes(attribute.String("subscription_id", id)))
+	defer func() { endSpan(span, err) }()
+	if err := validatePriceChange(change, s.now()); err != nil {
+		return err
+	}
+	if _, err := s.getOwned(ctx, id); err != nil {
+		return err
+	}
+	err = s.repo.SchedulePrice(ctx, id, change)
+	if err != nil {
+		return err
+	}
+	s.log(ctx).Info("Subscription price scheduled", zap.String("id", id), zap.Stringer("price", change.Price), zap.Time("effective_from", change.EffectiveFrom.Time))
+	return nil
+}
+
+func (s *SubscriptionService) List(ctx context.Context, params models.ListParams) (list *models.SubscriptionList, err error) {
+	ctx, span := tracer.Start(ctx, "SubscriptionService.List", trace.WithAttributes(
+		attribute.String("user_id", params.UserID),
+		attribute.String("service_name", params.ServiceName),
+	))
+	defer func() { endSpan(span, err) }()
+	if params.UserID, err = scopeUserID(ctx, params.UserID); err != nil {
+		return nil, err
+	}
+	subs, total, err := s.repo.List(ctx, params)
 	if err != nil {
-		s.logger.Error("Failed to list subscriptions", zap.Error(err))
 		return nil, err
 	}
-	return subs, nil
+	span.SetAttributes(attribute.Int("rows", len(subs)), attribute.Int("total", total))
+	list = &models.SubscriptionList{
+		Items:  subs,
+		Total:  total,
+		Limit:  params.Limit,
+		Offset: params.Offset,
+	}
+	if next := params.Offset + len(subs); len(subs) > 0 && next < total {
+		list.NextCursor = EncodeCursor(next)
+	}
+	return list, nil
+}
+
+// CountActive возвращает число подписок, активных в текущем месяце, у всех арендаторов.
+func (s *SubscriptionService) CountActive(ctx context.Context) (total int, err error) {
+	ctx, span := tracer.Start(ctx, "SubscriptionService.CountActive")
+	defer func() { endSpan(span, err) }()
+	ctx = tenant.WithAllTenants(ctx)
+	now := monthStart(time.Now())
+	_, total, err = s.repo.List(ctx, models.ListParams{ActiveAt: &now})
+	return total, err
 }
 
-func (s *SubscriptionService) GetTotalCost(userID string, serviceName string, from, to time.Time) (int, error) {
-	subs, err := s.repo.List()
+// EncodeCursor упаковывает смещение следующей страницы в непрозрачный курсор.
+func EncodeCursor(offset int) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(strconv.Itoa(offset)))
+}
+
+// DecodeCursor возвращает смещение, закодированное в курсоре.
+func DecodeCursor(cursor string) (int, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(cursor)
 	if err != nil {
-		s.logger.Error("Failed to list subscriptions for total cost", zap.Error(err))
 		return 0, err
 	}
-	total := 0
-	for _, sub := range subs {
-		if userID != "" && sub.UserID != userID {
-			continue
+	offset, err := strconv.Atoi(string(raw))
+	if err != nil || offset < 0 {
+		return 0, fmt.Errorf("invalid cursor %q", cursor)
+	}
+	return offset, nil
+}
+
+func (s *SubscriptionService) GetTotalCost(ctx context.Context, userID string, serviceName string, from, to time.Time, currency models.Currency) (total models.Money, err error) {
+	start := time.Now()
+	defer func() { metrics.TotalCostDuration.Set(time.Since(start).Seconds()) }()
+	ctx, span := tracer.Start(ctx, "SubscriptionService.GetTotalCost", trace.WithAttributes(costAttributes(userID, serviceName, from, to, currency)...))
+	defer func() { endSpan(span, err) }()
+	if err := validatePeriod(from, to); err != nil {
+		return 0, err
+	}
+	if userID, err = scopeUserID(ctx, userID); err != nil {
+		return 0, err
+	}
+	entries, err := s.convertedCosts(ctx, userID, serviceName, from, to, "", currency)
+	if err != nil {
+		return 0, err
+	}
+	for _, e := range entries {
+		if total, err = total.Add(e.Cost); err != nil {
+			return 0, costOverflow(err)
 		}
-		if serviceName != "" && sub.ServiceName != serviceName {
-			continue
+	}
+	s.log(ctx).Info("Calculated total cost", zap.String("user_id", userID), zap.String("service_name", serviceName), zap.Time("from", from), zap.Time("to", to), zap.String("currency", string(currency)), zap.Stringer("total_cost", total))
+	return total, nil
+}
+
+func (s *SubscriptionService) GetCostBreakdown(ctx context.Context, userID string, serviceName string, from, to time.Time, groupBy string, currency models.Currency) (breakdown *models.CostBreakdown, err error) {
+	ctx, span := tracer.Start(ctx, "SubscriptionService.GetCostBreakdown", trace.WithAttributes(costAttributes(userID, serviceName, from, to, currency)...))
+	span.SetAttributes(attribute.String("group_by", groupBy))
+	defer func() { endSpan(span, err) }()
+	if err := validatePeriod(from, to); err != nil {
+		return nil, err
+	}
+	if userID, err = scopeUserID(ctx, userID); err != nil {
+		return nil, err
+	}
+	entries, err := s.convertedCosts(ctx, userID, serviceName, from, to, groupBy, currency)
+	if err != nil {
+		return nil, err
+	}
+	if groupBy == "" {
+		entries = fillMonths(entries, from, to, currency)
+	}
+	breakdown = &models.CostBreakdown{GroupBy: groupBy, Currency: currency, Items: entries}
+	for _, e := range entries {
+		if breakdown.TotalCost, err = breakdown.TotalCost.Add(e.Cost); err != nil {
+			return nil, costOverflow(err)
 		}
-		start := sub.StartDate.Time
-		end := time.Now()
-		if sub.EndDate != nil {
-			end = sub.EndDate.Time
+	}
+	s.log(ctx).Info("Calculated cost breakdown", zap.String("user_id", userID), zap.String("service_name", serviceName), zap.Time("from", from), zap.Time("to", to), zap.String("group_by", groupBy), zap.String("currency", string(currency)), zap.Stringer("total_cost", breakdown.TotalCost))
+	return breakdown, nil
+}
+
+// convertedCosts пересчитывает помесячные суммы из репозитория в валюту currency по курсу
+// каждого месяца и объединяет записи одного месяца и группы.
+func (s *SubscriptionService) convertedCosts(ctx context.Context, userID string, serviceName string, from, to time.Time, groupBy string, currency models.Currency) ([]models.CostEntry, error) {
+	entries, err := s.repo.CostBreakdown(ctx, userID, serviceName, from, to, groupBy)
+	if err != nil {
+		return nil, err
+	}
+	trace.SpanFromContext(ctx).SetAttributes(attribute.Int("rows", len(entries)))
+	type key struct {
+		month time.Time
+		group string
+	}
+	type rateKey struct {
+		currency models.Currency
+		month    time.Time
+	}
+	index := make(map[key]int, len(entries))
+	// rates кеширует курсы на время вызова: записей на один месяц и валюту столько же,
+	// сколько групп, а каждый запрос курса — до двух обращений к БД.
+	rates := make(map[rateKey]*big.Rat)
+	converted := []models.CostEntry{}
+	for _, e := range entries {
+		rk := rateKey{currency: e.Currency, month: monthStart(e.Month.Time)}
+		rate, cached := rates[rk]
+		if !cached {
+			var ok bool
+			rate, ok, err = s.rates.Rate(ctx, e.Currency, currency, e.Month.Time)
+			if err != nil {
+				return nil, err
+			}
+			if !ok {
+				return nil, fmt.Errorf("%w: %s to %s for %s", errs.ErrRateNotFound, e.Currency, currency, e.Month.Format("01-2006"))
+			}
+			rates[rk] = rate
 		}
-		if end.Before(from) || start.After(to) {
+		cost, err := e.Cost.Mul(rate)
+		if err != nil {
+			return nil, costOverflow(err)
+		}
+		k := key{month: monthStart(e.Month.Time), group: e.Group}
+		if i, seen := index[k]; seen {
+			if converted[i].Cost, err = converted[i].Cost.Add(cost); err != nil {
+				return nil, costOverflow(err)
+			}
 			continue
 		}
-		actualStart := maxDate(start, from)
-		actualEnd := minDate(end, to)
-		months := diffMonths(actualStart, actualEnd)
-
-		total += sub.Price * months
+		index[k] = len(converted)
+		converted = append(converted, models.CostEntry{Month: e.Month, Group: e.Group, Currency: currency, Cost: cost})
 	}
-	s.logger.Info("Calculated total cost", zap.String("user_id", userID), zap.String("service_name", serviceName), zap.Time("from", from), zap.Time("to", to), zap.Int("total_cost", total))
-	return total, nil
+	return converted, nil
 }
 
-func maxDate(a, b time.Time) time.Time {
-	if a.After(b) {
-		return a
+// fillMonths дополняет разбивку без группировки нулевыми записями за месяцы без списаний,
+// чтобы каждый месяц периода from..to присутствовал ровно один раз.
+func fillMonths(entries []models.CostEntry, from, to time.Time, currency models.Currency) []models.CostEntry {
+	costs := make(map[time.Time]models.Money, len(entries))
+	for _, e := range entries {
+		costs[monthStart(e.Month.Time)] = e.Cost
 	}
-	return b
+	filled := []models.CostEntry{}
+	for m := monthStart(from); !m.After(to); m = m.AddDate(0, 1, 0) {
+		filled = append(filled, models.CostEntry{Month: models.MonthYear{Time: m}, Currency: currency, Cost: costs[m]})
+	}
+	return filled
 }
 
-func minDate(a, b time.Time) time.Time {
-	if a.Before(b) {
-		return a
+func costAttributes(userID, serviceName string, from, to time.Time, currency models.Currency) []attribute.KeyValue {
+	return []attribute.KeyValue{
+		attribute.String("user_id", userID),
+		attribute.String("service_name", serviceName),
+		attribute.String("from", from.Format("01-2006")),
+		attribute.String("to", to.Format("01-2006")),
+		attribute.String("currency", string(currency)),
 	}
-	return b
 }
 
-func diffMonths(from, to time.Time) int {
-	from = time.Date(from.Year(), from.Month(), 1, 0, 0, 0, 0, time.UTC)
-	to = time.Date(to.Year(), to.Month(), 1, 0, 0, 0, 0, time.UTC)
-	months := int(to.Month()) - int(from.Month()) + 12*(to.Year()-from.Year()) + 1
-	if months < 0 {
-		return 0
-	}
-	return months
+func monthStart(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, time.UTC)
 }
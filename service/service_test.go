@@ -0,0 +1,299 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"github.com/Tommych123/subscription-service/models"
+	"github.com/Tommych123/subscription-service/repository"
+	"github.com/Tommych123/subscription-service/service/auth"
+	"github.com/Tommych123/subscription-service/service/errs"
+	"go.uber.org/zap"
+	"slices"
+	"testing"
+	"time"
+)
+
+const bob = "22222222-2222-2222-2222-222222222222"
+
+// newTestService возвращает сервис поверх хранилищ в памяти; текущим считается январь 2025.
+func newTestService(t *testing.T) *SubscriptionService {
+	t.Helper()
+	s := NewSubscriptionService(repository.NewMemorySubscriptionRepository(), repository.NewMemoryExchangeRateRepository(), zap.NewNop())
+	s.now = func() time.Time { return time.Date(2025, 1, 15, 12, 0, 0, 0, time.UTC) }
+	return s
+}
+
+func asUser(userID string) context.Context {
+	return auth.WithPrincipal(context.Background(), &auth.Principal{
+		UserID:      userID,
+		Permissions: []string{auth.PermSubscriptionsRead, auth.PermSubscriptionsWrite, auth.PermTotalsRead},
+	})
+}
+
+func asAdmin() context.Context {
+	return auth.WithPrincipal(context.Background(), &auth.Principal{
+		UserID:      "33333333-3333-3333-3333-333333333333",
+		Permissions: []string{auth.PermSubscriptionsRead, auth.PermAllUsers},
+	})
+}
+
+func create(t *testing.T, s *SubscriptionService, sub models.Subscription) string {
+	t.Helper()
+	if sub.Currency == "" {
+		sub.Currency = models.DefaultCurrency
+	}
+	if sub.BillingPeriod == "" {
+		sub.BillingPeriod = models.BillingMonthly
+	}
+	id, err := s.Create(context.Background(), &sub)
+	if err != nil {
+		t.Fatalf("Create(%+v) error: %v", sub, err)
+	}
+	return id
+}
+
+func TestUserScoping(t *testing.T) {
+	s := newTestService(t)
+	aliceSub := create(t, s, models.Subscription{ServiceName: "Spotify", Price: 40000, UserID: alice, StartDate: month(t, "01-2025")})
+	bobSub := create(t, s, models.Subscription{ServiceName: "Netflix", Price: 80000, UserID: bob, StartDate: month(t, "01-2025")})
+
+	ctx := asUser(alice)
+	if _, err := s.GetByID(ctx, aliceSub); err != nil {
+		t.Errorf("GetByID(own) error: %v", err)
+	}
+	if _, err := s.GetByID(ctx, bobSub); !errors.Is(err, errs.ErrNotFound) {
+		t.Errorf("GetByID(other user's) error = %v, want ErrNotFound", err)
+	}
+	if err := s.Delete(ctx, bobSub); !errors.Is(err, errs.ErrNotFound) {
+		t.Errorf("Delete(other user's) error = %v, want ErrNotFound", err)
+	}
+	name := "Hijacked"
+	if _, err := s.Patch(ctx, bobSub, &models.SubscriptionPatch{ServiceName: &name}); !errors.Is(err, errs.ErrNotFound) {
+		t.Errorf("Patch(other user's) error = %v, want ErrNotFound", err)
+	}
+	foreign := models.Subscription{ServiceName: "Spotify", Price: 100, Currency: models.DefaultCurrency, UserID: bob, StartDate: month(t, "01-2025"), BillingPeriod: models.BillingMonthly}
+	if _, err := s.Create(ctx, &foreign); !errors.Is(err, errs.ErrForbidden) {
+		t.Errorf("Create(for other user) error = %v, want ErrForbidden", err)
+	}
+	moved := models.Subscription{ID: aliceSub, ServiceName: "Spotify", Price: 40000, Currency: models.DefaultCurrency, UserID: bob, StartDate: month(t, "01-2025"), BillingPeriod: models.BillingMonthly}
+	if err := s.Update(ctx, &moved); !errors.Is(err, errs.ErrForbidden) {
+		t.Errorf("Update(move to other user) error = %v, want ErrForbidden", err)
+	}
+	toBob := bob
+	if _, err := s.Patch(ctx, aliceSub, &models.SubscriptionPatch{UserID: &toBob}); !errors.Is(err, errs.ErrForbidden) {
+		t.Errorf("Patch(move to other user) error = %v, want ErrForbidden", err)
+	}
+	if sub, err := s.GetByID(ctx, aliceSub); err != nil || sub.UserID != alice {
+		t.Errorf("rejected patch moved subscription: %+v, %v", sub, err)
+	}
+
+	list, err := s.List(ctx, models.ListParams{Limit: 50})
+	if err != nil {
+		t.Fatalf("List() error: %v", err)
+	}
+	if list.Total != 1 || list.Items[0].ID != aliceSub {
+		t.Errorf("List() = %+v, want only alice's subscription", list.Items)
+	}
+	if _, err := s.List(ctx, models.ListParams{Limit: 50, UserID: bob}); !errors.Is(err, errs.ErrForbidden) {
+		t.Errorf("List(user_id=bob) error = %v, want ErrForbidden", err)
+	}
+	from, to := month(t, "01-2025").Time, month(t, "01-2025").Time
+	total, err := s.GetTotalCost(ctx, "", "", from, to, models.DefaultCurrency)
+	if err != nil {
+		t.Fatalf("GetTotalCost() error: %v", err)
+	}
+	if total != 40000 {
+		t.Errorf("GetTotalCost() = %s, want 400.00 (alice only)", total)
+	}
+	if _, err := s.GetCostBreakdown(ctx, bob, "", from, to, "", models.DefaultCurrency); !errors.Is(err, errs.ErrForbidden) {
+		t.Errorf("GetCostBreakdown(user_id=bob) error = %v, want ErrForbidden", err)
+	}
+
+	admin := asAdmin()
+	if _, err := s.GetByID(admin, bobSub); err != nil {
+		t.Errorf("GetByID as admin error: %v", err)
+	}
+	list, err = s.List(admin, models.ListParams{Limit: 50})
+	if err != nil {
+		t.Fatalf("List as admin error: %v", err)
+	}
+	if list.Total != 2 {
+		t.Errorf("List as admin total = %d, want 2", list.Total)
+	}
+	total, err = s.GetTotalCost(admin, "", "", from, to, models.DefaultCurrency)
+	if err != nil {
+		t.Fatalf("GetTotalCost as admin error: %v", err)
+	}
+	if total != 120000 {
+		t.Errorf("GetTotalCost as admin = %s, want 1200.00", total)
+	}
+}
+
+func TestCreateValidation(t *testing.T) {
+	valid := func() models.Subscription {
+		return models.Subscription{
+			ServiceName:   "Spotify",
+			Price:         40000,
+			Currency:      models.DefaultCurrency,
+			UserID:        alice,
+			StartDate:     models.MonthYear{Time: time.Date(2025, 3, 1, 0, 0, 0, 0, time.UTC)},
+			BillingPeriod: models.BillingMonthly,
+		}
+	}
+	tests := []struct {
+		name   string
+		modify func(*models.Subscription)
+		want   []string
+	}{
+		{name: "empty service name", modify: func(s *models.Subscription) { s.ServiceName = "  " }, want: []string{"service_name"}},
+		{name: "negative price", modify: func(s *models.Subscription) { s.Price = -1 }, want: []string{"price"}},
+		{name: "invalid user id", modify: func(s *models.Subscription) { s.UserID = "alice" }, want: []string{"user_id"}},
+		{name: "missing start date", modify: func(s *models.Subscription) { s.StartDate = models.MonthYear{} }, want: []string{"start_date"}},
+		{
+			name: "end before start",
+			modify: func(s *models.Subscription) {
+				s.EndDate = &models.MonthYear{Time: time.Date(2025, 2, 1, 0, 0, 0, 0, time.UTC)}
+			},
+			want: []string{"end_date"},
+		},
+		{
+			name: "several fields",
+			modify: func(s *models.Subscription) {
+				s.ServiceName = ""
+				s.Price = -100
+				s.UserID = ""
+			},
+			want: []string{"service_name", "price", "user_id"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newTestService(t)
+			sub := valid()
+			tt.modify(&sub)
+			_, err := s.Create(context.Background(), &sub)
+			if got := fieldErrors(t, err); !slices.Equal(got, tt.want) {
+				t.Errorf("invalid fields = %v, want %v", got, tt.want)
+			}
+			list, err := s.List(context.Background(), models.ListParams{Limit: 50})
+			if err != nil {
+				t.Fatalf("List() error: %v", err)
+			}
+			if list.Total != 0 {
+				t.Errorf("invalid subscription was stored: %+v", list.Items)
+			}
+		})
+	}
+}
+
+func TestPriceChangeValidation(t *testing.T) {
+	s := newTestService(t)
+	id := create(t, s, models.Subscription{ServiceName: "Spotify", Price: 40000, UserID: alice, StartDate: month(t, "01-2024")})
+	ctx := context.Background()
+
+	sub, err := s.GetByID(ctx, id)
+	if err != nil {
+		t.Fatalf("GetByID() error: %v", err)
+	}
+	sub.Price = 50000
+	if got := fieldErrors(t, s.Update(ctx, sub)); !slices.Equal(got, []string{"price"}) {
+		t.Errorf("Update(new price) invalid fields = %v, want [price]", got)
+	}
+	price := models.Money(50000)
+	_, err = s.Patch(ctx, id, &models.SubscriptionPatch{Price: &price})
+	if got := fieldErrors(t, err); !slices.Equal(got, []string{"price"}) {
+		t.Errorf("Patch(new price) invalid fields = %v, want [price]", got)
+	}
+
+	tests := []struct {
+		name   string
+		change models.PriceChange
+		want   []string
+	}{
+		{name: "previous month", change: models.PriceChange{Price: 50000, EffectiveFrom: month(t, "12-2024")}, want: []string{"effective_from"}},
+		{name: "missing effective_from", change: models.PriceChange{Price: 50000}, want: []string{"effective_from"}},
+		{name: "negative price", change: models.PriceChange{Price: -1, EffectiveFrom: month(t, "02-2025")}, want: []string{"price"}},
+		{name: "current month", change: models.PriceChange{Price: 50000, EffectiveFrom: month(t, "01-2025")}},
+		{name: "future month", change: models.PriceChange{Price: 60000, EffectiveFrom: month(t, "06-2025")}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := s.SchedulePrice(ctx, id, tt.change)
+			if tt.want == nil {
+				if err != nil {
+					t.Fatalf("SchedulePrice() error: %v", err)
+				}
+				return
+			}
+			if got := fieldErrors(t, err); !slices.Equal(got, tt.want) {
+				t.Errorf("invalid fields = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPatchMergeSemantics(t *testing.T) {
+	s := newTestService(t)
+	ctx := context.Background()
+	id := create(t, s, models.Subscription{
+		ServiceName: "Spotify",
+		Price:       40000,
+		UserID:      alice,
+		StartDate:   month(t, "01-2025"),
+		EndDate:     monthPtr(t, "12-2025"),
+	})
+
+	patchWith := func(t *testing.T, body string) *models.SubscriptionPatch {
+		t.Helper()
+		var patch models.SubscriptionPatch
+		if err := json.Unmarshal([]byte(body), &patch); err != nil {
+			t.Fatalf("unmarshal %s: %v", body, err)
+		}
+		return &patch
+	}
+
+	sub, err := s.Patch(ctx, id, patchWith(t, `{"service_name":"Spotify Family"}`))
+	if err != nil {
+		t.Fatalf("Patch(service_name) error: %v", err)
+	}
+	if sub.ServiceName != "Spotify Family" || sub.Price != 40000 || sub.EndDate == nil || !sub.EndDate.Equal(month(t, "12-2025").Time) {
+		t.Errorf("Patch(service_name) = %+v, want only service_name changed", sub)
+	}
+
+	if _, err := s.Patch(ctx, id, patchWith(t, `{"end_date":"12-2024"}`)); !slices.Equal(fieldErrors(t, err), []string{"end_date"}) {
+		t.Errorf("Patch(end_date before start) error = %v, want end_date validation error", err)
+	}
+	stored, err := s.GetByID(ctx, id)
+	if err != nil {
+		t.Fatalf("GetByID() error: %v", err)
+	}
+	if stored.EndDate == nil || !stored.EndDate.Equal(month(t, "12-2025").Time) {
+		t.Errorf("rejected patch changed end_date to %v", stored.EndDate)
+	}
+
+	sub, err = s.Patch(ctx, id, patchWith(t, `{"end_date":null}`))
+	if err != nil {
+		t.Fatalf("Patch(end_date: null) error: %v", err)
+	}
+	if sub.EndDate != nil {
+		t.Errorf("Patch(end_date: null) end_date = %v, want nil", sub.EndDate)
+	}
+	if sub.ServiceName != "Spotify Family" {
+		t.Errorf("Patch(end_date: null) service_name = %q, want unchanged", sub.ServiceName)
+	}
+	stored, err = s.GetByID(ctx, id)
+	if err != nil {
+		t.Fatalf("GetByID() error: %v", err)
+	}
+	if stored.EndDate != nil {
+		t.Errorf("stored end_date = %v, want nil", stored.EndDate)
+	}
+
+	for _, body := range []string{`{"service_name":null}`, `{"price":null}`, `{"id":"x"}`, `[]`, `null`} {
+		var patch models.SubscriptionPatch
+		if err := json.Unmarshal([]byte(body), &patch); err == nil {
+			t.Errorf("unmarshal %s: want error", body)
+		}
+	}
+}
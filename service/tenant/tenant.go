@@ -0,0 +1,46 @@
+// Package tenant хранит в контексте запроса арендатора (бизнес-подразделение), данными
+// которого ограничиваются все запросы к хранилищу подписок.
+package tenant
+
+import (
+	"context"
+	"regexp"
+)
+
+// Default — арендатор, к которому относятся данные, созданные до разделения по арендаторам.
+const Default = "default"
+
+var idPattern = regexp.MustCompile(`^[A-Za-z0-9_-]{1,64}$`)
+
+// Valid сообщает, является ли id допустимым идентификатором арендатора.
+func Valid(id string) bool {
+	return idPattern.MatchString(id)
+}
+
+type ctxKey struct{}
+
+type allTenantsKey struct{}
+
+// WithTenant возвращает копию ctx с арендатором id.
+func WithTenant(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, ctxKey{}, id)
+}
+
+// FromContext возвращает арендатора запроса. ok == false только для внутренних вызовов
+// сервиса вне HTTP-запроса (например, сбора метрик).
+func FromContext(ctx context.Context) (id string, ok bool) {
+	id, ok = ctx.Value(ctxKey{}).(string)
+	return id, ok
+}
+
+// WithAllTenants помечает ctx внутренней задачи, которой нужны данные всех арендаторов.
+// При включённой row-level security запросы без арендатора и без этой пометки не видят ни одной строки.
+func WithAllTenants(ctx context.Context) context.Context {
+	return context.WithValue(ctx, allTenantsKey{}, true)
+}
+
+// AllTenants сообщает, помечен ли ctx через WithAllTenants.
+func AllTenants(ctx context.Context) bool {
+	all, _ := ctx.Value(allTenantsKey{}).(bool)
+	return all
+}
@@ -0,0 +1,95 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"github.com/Tommych123/subscription-service/models"
+	"github.com/Tommych123/subscription-service/service/errs"
+	"github.com/google/uuid"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+const maxServiceNameLength = 255
+
+// validator накапливает ошибки по полям и возвращает их одной *errs.ValidationError.
+type validator struct {
+	fields []errs.FieldError
+}
+
+func (v *validator) check(ok bool, field, message string) {
+	if !ok {
+		v.fields = append(v.fields, errs.FieldError{Field: field, Message: message})
+	}
+}
+
+func (v *validator) err() error {
+	if len(v.fields) == 0 {
+		return nil
+	}
+	return &errs.ValidationError{Fields: v.fields}
+}
+
+func validateSubscription(sub *models.Subscription) error {
+	var v validator
+	name := strings.TrimSpace(sub.ServiceName)
+	v.check(name != "", "service_name", "must not be empty")
+	v.check(utf8.RuneCountInString(name) <= maxServiceNameLength, "service_name", "must be at most 255 characters")
+	v.check(sub.Price >= 0, "price", "must not be negative")
+	_, err := uuid.Parse(sub.UserID)
+	v.check(err == nil, "user_id", "must be a valid UUID")
+	v.check(!sub.StartDate.IsZero(), "start_date", "is required")
+	if sub.EndDate != nil && !sub.StartDate.IsZero() {
+		v.check(!sub.EndDate.Before(sub.StartDate.Time), "end_date", "must not be before start_date")
+	}
+	return v.err()
+}
+
+// validatePriceChange допускает новую цену только с текущего месяца или позже, чтобы не менять
+// уже прошедшие списания.
+func validatePriceChange(change models.PriceChange, now time.Time) error {
+	var v validator
+	v.check(change.Price >= 0, "price", "must not be negative")
+	v.check(!change.EffectiveFrom.IsZero(), "effective_from", "is required")
+	if !change.EffectiveFrom.IsZero() {
+		v.check(!change.EffectiveFrom.Before(monthStart(now)), "effective_from", "must not be before the current month")
+	}
+	return v.err()
+}
+
+// validatePriceUnchanged запрещает менять цену при обновлении подписки: исходная цена действует
+// с start_date, и её изменение пересчитало бы прошлые итоги. Цена меняется через SchedulePrice.
+func validatePriceUnchanged(current, next *models.Subscription) error {
+	var v validator
+	v.check(next.Price == current.Price, "price", "cannot be changed by update, use PUT /subscriptions/{id}/price")
+	return v.err()
+}
+
+func validatePeriod(from, to time.Time) error {
+	var v validator
+	v.check(!from.After(to), "to", "must not be before from")
+	return v.err()
+}
+
+// costOverflow переводит переполнение суммы при расчёте итогов в ошибку проверки:
+// итог за запрошенный период не помещается в Money.
+func costOverflow(err error) error {
+	if !errors.Is(err, models.ErrMoneyOverflow) {
+		return err
+	}
+	var v validator
+	v.check(false, "total_cost", "is out of range")
+	return v.err()
+}
+
+func validateNewAPIKey(req models.NewAPIKey, now time.Time) error {
+	v := &validator{}
+	v.check(strings.TrimSpace(req.Name) != "", "name", "must not be empty")
+	v.check(len(req.Scopes) > 0, "scopes", "must not be empty")
+	for _, scope := range req.Scopes {
+		v.check(models.APIKeyScopes[scope], "scopes", fmt.Sprintf("unknown scope %q", scope))
+	}
+	v.check(req.ExpiresAt == nil || req.ExpiresAt.After(now), "expires_at", "must be in the future")
+	return v.err()
+}
@@ -0,0 +1,133 @@
+package service
+
+import (
+	"errors"
+	"github.com/Tommych123/subscription-service/models"
+	"github.com/Tommych123/subscription-service/service/errs"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+const alice = "11111111-1111-1111-1111-111111111111"
+
+func month(t *testing.T, s string) models.MonthYear {
+	t.Helper()
+	m, err := time.Parse("01-2006", s)
+	if err != nil {
+		t.Fatalf("parse month %q: %v", s, err)
+	}
+	return models.MonthYear{Time: m}
+}
+
+func monthPtr(t *testing.T, s string) *models.MonthYear {
+	m := month(t, s)
+	return &m
+}
+
+// fieldErrors возвращает поля из *errs.ValidationError; nil — если ошибки нет.
+func fieldErrors(t *testing.T, err error) []string {
+	t.Helper()
+	if err == nil {
+		return nil
+	}
+	var verr *errs.ValidationError
+	if !errors.As(err, &verr) {
+		t.Fatalf("error = %v, want *errs.ValidationError", err)
+	}
+	fields := []string{}
+	for _, f := range verr.Fields {
+		fields = append(fields, f.Field)
+	}
+	return fields
+}
+
+func TestValidateSubscription(t *testing.T) {
+	valid := func() models.Subscription {
+		return models.Subscription{
+			ServiceName:   "Spotify",
+			Price:         40000,
+			Currency:      models.DefaultCurrency,
+			UserID:        alice,
+			StartDate:     month(t, "03-2025"),
+			BillingPeriod: models.BillingMonthly,
+		}
+	}
+	tests := []struct {
+		name   string
+		modify func(*models.Subscription)
+		want   []string
+	}{
+		{name: "valid", modify: func(s *models.Subscription) {}},
+		{name: "empty service name", modify: func(s *models.Subscription) { s.ServiceName = "  " }, want: []string{"service_name"}},
+		{name: "255 cyrillic characters", modify: func(s *models.Subscription) { s.ServiceName = strings.Repeat("я", 255) }},
+		{name: "256 characters", modify: func(s *models.Subscription) { s.ServiceName = strings.Repeat("я", 256) }, want: []string{"service_name"}},
+		{name: "negative price", modify: func(s *models.Subscription) { s.Price = -1 }, want: []string{"price"}},
+		{name: "invalid user id", modify: func(s *models.Subscription) { s.UserID = "alice" }, want: []string{"user_id"}},
+		{name: "missing start date", modify: func(s *models.Subscription) { s.StartDate = models.MonthYear{} }, want: []string{"start_date"}},
+		{name: "end before start", modify: func(s *models.Subscription) { s.EndDate = monthPtr(t, "02-2025") }, want: []string{"end_date"}},
+		{name: "end in start month", modify: func(s *models.Subscription) { s.EndDate = monthPtr(t, "03-2025") }},
+		{
+			name: "several fields",
+			modify: func(s *models.Subscription) {
+				s.ServiceName = ""
+				s.Price = -100
+				s.UserID = ""
+			},
+			want: []string{"service_name", "price", "user_id"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sub := valid()
+			tt.modify(&sub)
+			if got := fieldErrors(t, validateSubscription(&sub)); !slices.Equal(got, tt.want) {
+				t.Errorf("invalid fields = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestValidatePriceChange(t *testing.T) {
+	now := time.Date(2025, 1, 15, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name   string
+		change models.PriceChange
+		want   []string
+	}{
+		{name: "previous month", change: models.PriceChange{Price: 50000, EffectiveFrom: month(t, "12-2024")}, want: []string{"effective_from"}},
+		{name: "missing effective_from", change: models.PriceChange{Price: 50000}, want: []string{"effective_from"}},
+		{name: "negative price", change: models.PriceChange{Price: -1, EffectiveFrom: month(t, "02-2025")}, want: []string{"price"}},
+		{name: "current month", change: models.PriceChange{Price: 50000, EffectiveFrom: month(t, "01-2025")}},
+		{name: "future month", change: models.PriceChange{Price: 60000, EffectiveFrom: month(t, "06-2025")}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := fieldErrors(t, validatePriceChange(tt.change, now)); !slices.Equal(got, tt.want) {
+				t.Errorf("invalid fields = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestValidatePriceUnchanged(t *testing.T) {
+	current := &models.Subscription{Price: 40000}
+	if err := validatePriceUnchanged(current, &models.Subscription{Price: 40000}); err != nil {
+		t.Errorf("same price: error %v", err)
+	}
+	if got := fieldErrors(t, validatePriceUnchanged(current, &models.Subscription{Price: 50000})); !slices.Equal(got, []string{"price"}) {
+		t.Errorf("new price: invalid fields = %v, want [price]", got)
+	}
+}
+
+func TestCostOverflow(t *testing.T) {
+	_, err := models.Money(1 << 62).Add(1 << 62)
+	if got := fieldErrors(t, costOverflow(err)); !slices.Equal(got, []string{"total_cost"}) {
+		t.Errorf("overflow: invalid fields = %v, want [total_cost]", got)
+	}
+	other := errors.New("db is down")
+	if got := costOverflow(other); got != other {
+		t.Errorf("costOverflow(other) = %v, want the error unchanged", got)
+	}
+}